@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Colors derived from the version when --color isn't given
+const (
+	badgeColorStable     string = "blue"
+	badgeColorPrerelease string = "orange"
+)
+
+// The named colors shields.io knows, for --svg
+var badgeColors = map[string]string{
+	"brightgreen": "#4c1",
+	"green":       "#97ca00",
+	"yellowgreen": "#a4a61d",
+	"yellow":      "#dfb317",
+	"orange":      "#fe7d37",
+	"red":         "#e05d44",
+	"blue":        "#007ec6",
+	"grey":        "#555",
+	"gray":        "#555",
+	"lightgrey":   "#9f9f9f",
+	"lightgray":   "#9f9f9f",
+}
+
+var hexColor = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// The shields.io endpoint schema, https://shields.io/badges/endpoint-badge
+type badgeEndpoint struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// Builds the badge for v, picking the color from whether v is a prerelease
+// when color is empty
+func newBadge(v *version.GoVersion, label, color string) badgeEndpoint {
+	if color == "" {
+		color = badgeColorStable
+		if v.Version.Prerelease() != "" {
+			color = badgeColorPrerelease
+		}
+	}
+	return badgeEndpoint{
+		SchemaVersion: 1,
+		Label:         label,
+		Message:       settings.ValuePrefix() + v.Version.String(),
+		Color:         color,
+	}
+}
+
+// Writes the badge as endpoint JSON or, with svg, as a flat badge image, to
+// output or stdout
+func writeBadge(v *version.GoVersion, label, color, output string, svg bool) {
+	badge := newBadge(v, label, color)
+
+	var contents []byte
+	if svg {
+		fill, ok := badgeFill(badge.Color)
+		if !ok {
+			names := make([]string, 0, len(badgeColors))
+			for name := range badgeColors {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			printError("Unknown color '%s', use a hex color or one of: %s\n", badge.Color, strings.Join(names, ", "))
+			exit(exitUsage)
+		}
+		contents = []byte(badgeSVG(badge, fill))
+	} else {
+		encoded, err := json.Marshal(badge)
+		if err != nil {
+			printError("Unable to marshal badge\n")
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitFailure)
+		}
+		contents = append(encoded, '\n')
+	}
+
+	if output == "" {
+		stdout.Write(contents)
+		return
+	}
+	if err := os.WriteFile(output, contents, 0644); err != nil {
+		printError("Unable to write %s\n", output)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	logVerbose("Wrote %s", output)
+}
+
+func badgeFill(color string) (string, bool) {
+	if fill, ok := badgeColors[strings.ToLower(color)]; ok {
+		return fill, true
+	}
+	if hexColor.MatchString(color) {
+		return "#" + strings.TrimPrefix(color, "#"), true
+	}
+	return "", false
+}
+
+// Width of text in the badge font, Verdana at 11px averages close to 7px a
+// character. Close enough for a static badge without shipping font metrics.
+func badgeTextWidth(text string) int {
+	return len([]rune(text))*7 + 10
+}
+
+// Renders a flat badge in the style of shields.io
+func badgeSVG(badge badgeEndpoint, fill string) string {
+	label, message := html.EscapeString(badge.Label), html.EscapeString(badge.Message)
+	labelWidth, messageWidth := badgeTextWidth(badge.Label), badgeTextWidth(badge.Message)
+	width := labelWidth + messageWidth
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`+"\n", width, label, message)
+	fmt.Fprintf(&b, "  <title>%s: %s</title>\n", label, message)
+	b.WriteString(`  <linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>` + "\n")
+	fmt.Fprintf(&b, `  <clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`+"\n", width)
+	b.WriteString(`  <g clip-path="url(#r)">` + "\n")
+	fmt.Fprintf(&b, `    <rect width="%d" height="20" fill="#555"/>`+"\n", labelWidth)
+	fmt.Fprintf(&b, `    <rect x="%d" width="%d" height="20" fill="%s"/>`+"\n", labelWidth, messageWidth, fill)
+	fmt.Fprintf(&b, `    <rect width="%d" height="20" fill="url(#s)"/>`+"\n", width)
+	b.WriteString("  </g>\n")
+	b.WriteString(`  <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">` + "\n")
+	fmt.Fprintf(&b, `    <text x="%d" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%d" y="14">%s</text>`+"\n", labelWidth/2, label, labelWidth/2, label)
+	fmt.Fprintf(&b, `    <text x="%d" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%d" y="14">%s</text>`+"\n", labelWidth+messageWidth/2, message, labelWidth+messageWidth/2, message)
+	b.WriteString("  </g>\n")
+	b.WriteString("</svg>\n")
+	return b.String()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+const changelogFileName string = "CHANGELOG.md"
+
+const changelogHeader string = `# Changelog
+
+All notable changes to this project are documented in this file.
+`
+
+// Subjects of the commits since the previous release, empty when they can't
+// be read so that the changelog still gets a section for the new version
+func changelogEntries(previous *version.GoVersion) []string {
+	if _, err := runGit("rev-parse", "--is-inside-work-tree"); err != nil {
+		logVerbose("Not in a git repository, adding an empty changelog section")
+		return nil
+	}
+
+	since, commits, err := commitsSinceRelease(previous)
+	if err != nil {
+		printWarning("Unable to read commits since %s, adding an empty changelog section: %s\n", since, err)
+		return nil
+	}
+
+	entries := make([]string, 0, len(commits))
+	for _, c := range commits {
+		entries = append(entries, c.subject)
+	}
+	return entries
+}
+
+// Inserts a section for the new version above the previous ones in the
+// changelog next to the version file, creating the file if needed. Returns
+// the changelog path, or an empty string when the section already existed.
+func updateChangelog(previous, v *version.GoVersion) string {
+	path := filepath.Join(filepath.Dir(versionFile), changelogFileName)
+
+	contents, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		contents, err = []byte(changelogHeader), nil
+	}
+	if err != nil {
+		printError("Unable to read %s\n", path)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+
+	heading := "## " + v.Version.String()
+	for _, line := range strings.Split(string(contents), "\n") {
+		if line == heading || strings.HasPrefix(line, heading+" ") {
+			logVerbose("%s already has a section for %s", changelogFileName, v.Version)
+			return ""
+		}
+	}
+
+	var section strings.Builder
+	fmt.Fprintf(&section, "%s - %s (%s)\n\n", heading, time.Now().Format("2006-01-02"), v.VersionString)
+	for _, entry := range changelogEntries(previous) {
+		fmt.Fprintf(&section, "- %s\n", entry)
+	}
+	section.WriteString("\n")
+
+	// New sections go above the newest one, or after everything else when
+	// there are none yet
+	var updated string
+	text := string(contents)
+	if strings.HasPrefix(text, "## ") {
+		updated = section.String() + text
+	} else if i := strings.Index(text, "\n## "); i >= 0 {
+		updated = text[:i+1] + section.String() + text[i+1:]
+	} else {
+		updated = strings.TrimRight(text, "\n") + "\n\n" + strings.TrimRight(section.String(), "\n") + "\n"
+	}
+
+	if err := os.WriteFile(path, []byte(updated), 0644); err != nil {
+		printError("Unable to write %s\n", path)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	printInfo("Added %s to %s\n", displayVersion(v.Version), changelogFileName)
+	return path
+}
+
+// Body of v's section in the changelog next to the version file, without its
+// heading. Reports false when the changelog or the section doesn't exist.
+func changelogSection(v *version.GoVersion) (string, bool) {
+	path := filepath.Join(filepath.Dir(versionFile), changelogFileName)
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		logVerbose("Unable to read %s: %s", path, err)
+		return "", false
+	}
+
+	heading := "## " + v.Version.String()
+	var section []string
+	found := false
+	for _, line := range strings.Split(string(contents), "\n") {
+		if found && strings.HasPrefix(line, "## ") {
+			break
+		}
+		if found {
+			section = append(section, line)
+		}
+		found = found || line == heading || strings.HasPrefix(line, heading+" ")
+	}
+	return strings.TrimSpace(strings.Join(section, "\n")), found
+}
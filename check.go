@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Validates the version file without modifying it and returns every problem
+// found. The file is decoded loosely so that one bad field doesn't hide the
+// others. With schema the file is also validated against version.JSONSchema.
+func checkVersionFile(schema bool) []string {
+	var problems []string
+
+	leftovers, _ := filepath.Glob(version.TempFileGlob(versionFile))
+	for _, leftover := range leftovers {
+		problems = append(problems, fmt.Sprintf("temporary file %s was left behind by an interrupted write", leftover))
+	}
+
+	contents, err := os.ReadFile(versionFile)
+	if err != nil {
+		return append(problems, fmt.Sprintf("unable to read %s: %s", versionFile, err))
+	}
+
+	var fields map[string]json.RawMessage
+	jsonBytes, err := version.FormatFor(versionFile).ToJSON(contents)
+	if err == nil {
+		err = json.Unmarshal(jsonBytes, &fields)
+	}
+	if err != nil {
+		return append(problems, fmt.Sprintf("unable to parse %s: %s", versionFile, err))
+	}
+
+	if raw, ok := fields["schemaVersion"]; ok {
+		var schema int
+		if err := json.Unmarshal(raw, &schema); err != nil {
+			problems = append(problems, "schemaVersion is not an integer")
+		} else if schema > version.CurrentSchema {
+			problems = append(problems, fmt.Sprintf("schemaVersion %d is newer than this gover understands (%d), upgrade gover", schema, version.CurrentSchema))
+		}
+	}
+
+	var versionField string
+	if err := json.Unmarshal(fields["version"], &versionField); err != nil {
+		problems = append(problems, "version is missing or not a string")
+	} else if versionField == "" {
+		// null decodes without an error
+		problems = append(problems, "version is missing")
+	} else if _, err := semver.NewVersion(versionField); err != nil {
+		problems = append(problems, fmt.Sprintf("version '%s' is not valid semver", versionField))
+	}
+
+	// Nothing else is stored in a text version file
+	if textVersionFile() {
+		return problems
+	}
+
+	var build interface{}
+	decoder := json.NewDecoder(bytes.NewReader(fields["build"]))
+	decoder.UseNumber()
+	if err := decoder.Decode(&build); err != nil {
+		problems = append(problems, "build is missing")
+	} else if number, ok := build.(json.Number); !ok {
+		problems = append(problems, "build is not a number")
+	} else if n, err := number.Int64(); err != nil {
+		problems = append(problems, fmt.Sprintf("build %s is not an integer", number))
+	} else if n < 0 {
+		problems = append(problems, fmt.Sprintf("build %d is negative", n))
+	}
+
+	for _, key := range []string{"name", "versionString"} {
+		var value string
+		if err := json.Unmarshal(fields[key], &value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s is missing or not a string", key))
+		} else if value == "" {
+			problems = append(problems, fmt.Sprintf("%s is empty", key))
+		}
+	}
+
+	if raw, ok := fields["calver"]; ok {
+		var calver version.CalVer
+		if err := json.Unmarshal(raw, &calver); err != nil {
+			problems = append(problems, "calver is not an object with a pattern and timezone")
+		} else if err := calver.Validate(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	var resetBuild, bumpBuild bool
+	json.Unmarshal(fields["resetBuildOnBump"], &resetBuild)
+	json.Unmarshal(fields["bumpBuildOnVersionBump"], &bumpBuild)
+	if resetBuild && bumpBuild {
+		problems = append(problems, "resetBuildOnBump and bumpBuildOnVersionBump can't both be set")
+	}
+	var buildFromCI bool
+	var buildSource string
+	json.Unmarshal(fields["buildFromCI"], &buildFromCI)
+	json.Unmarshal(fields["buildSource"], &buildSource)
+	if buildFromCI && buildSource == version.BuildSourceGitCount {
+		problems = append(problems, "buildFromCI can't be combined with buildSource git-count")
+	}
+
+	if raw, ok := fields["buildSource"]; ok {
+		var source string
+		known := false
+		err := json.Unmarshal(raw, &source)
+		for _, s := range version.BuildSources {
+			known = known || s == source
+		}
+		if err != nil {
+			problems = append(problems, "buildSource is not a string")
+		} else if source != "" && !known {
+			problems = append(problems, fmt.Sprintf("buildSource '%s' is not one of: %s", source, strings.Join(version.BuildSources, ", ")))
+		}
+	}
+
+	if _, ok := fields["checksum"]; ok {
+		// Problems decoding the file have been reported above
+		if v, err := version.Decode(contents, version.FormatFor(versionFile)); err == nil {
+			if err := v.VerifyChecksum(); err != nil {
+				problems = append(problems, fmt.Sprintf("%s, it was modified outside of gover", err))
+			}
+		}
+	}
+
+	// The schema reports unknown fields itself
+	if !schema {
+		unknown, _ := version.UnknownFields(contents, version.FormatFor(versionFile))
+		for _, field := range unknown {
+			problems = append(problems, fmt.Sprintf("%s is not a known field, it will be dropped the next time the file is written", field))
+		}
+	}
+
+	if schema {
+		problems = append(problems, version.JSONSchema().Validate(jsonBytes)...)
+	}
+
+	return problems
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A CI provider and the environment variable holding its build number
+type ciProvider struct {
+	name string
+	// detect is set to a non-empty value in every job the provider runs
+	detect string
+	build  string
+}
+
+// Checked in order, the first provider detected supplies the build number
+var ciProviders = []ciProvider{
+	{name: "GitHub Actions", detect: "GITHUB_ACTIONS", build: "GITHUB_RUN_NUMBER"},
+	{name: "GitLab CI", detect: "GITLAB_CI", build: "CI_PIPELINE_IID"},
+	{name: "Jenkins", detect: "JENKINS_URL", build: "BUILD_NUMBER"},
+}
+
+// Build number of the current CI job. Exits listing the supported providers
+// when none is detected, rather than falling back to 0.
+func ciBuild() int {
+	for _, provider := range ciProviders {
+		if os.Getenv(provider.detect) == "" {
+			continue
+		}
+		value := os.Getenv(provider.build)
+		build, err := strconv.Atoi(value)
+		if err != nil || build < 0 {
+			printError("Running on %s, but %s is '%s' rather than a non-negative integer\n", provider.name, provider.build, value)
+			exit(exitFailure)
+		}
+		logVerbose("Build %d from %s %s", build, provider.name, provider.build)
+		return build
+	}
+
+	printError("No supported CI environment detected, the supported providers are:\n")
+	for _, provider := range ciProviders {
+		fmt.Fprintf(os.Stderr, "  %s (%s when %s is set)\n", provider.name, provider.build, provider.detect)
+	}
+	exit(exitFailure)
+	return 0
+}
+
+// Names of the supported providers for flag usage messages
+func ciProviderNames() string {
+	names := make([]string, 0, len(ciProviders))
+	for _, provider := range ciProviders {
+		names = append(names, provider.name)
+	}
+	return strings.Join(names, ", ")
+}
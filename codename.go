@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/subtlepseudonym/go-prompt"
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Fruit, after the default codename
+var codenameWords = []string{
+	"acerola", "ackee", "akee", "apple", "apricot", "avocado", "babaco",
+	"bael", "banana", "barberry", "bayberry", "bignay", "bilberry",
+	"bilimbi", "blackberry", "blackcurrant", "blueberry", "boysenberry",
+	"breadfruit", "buffaloberry", "calamansi", "camu", "canistel",
+	"cantaloupe", "capulin", "carambola", "chayote", "cherimoya", "cherry",
+	"chokeberry", "citron", "clementine", "cloudberry", "coconut",
+	"cornel", "crabapple", "cranberry", "cupuacu", "currant", "damask",
+	"damson", "date", "dewberry", "dragonfruit", "durian", "elderberry",
+	"feijoa", "fig", "gac", "genip", "goji", "gooseberry", "grape",
+	"grapefruit", "greengage", "grumichama", "guava", "hackberry",
+	"hawthorn", "honeyberry", "honeydew", "huckleberry", "illawarra",
+	"imbe", "jaboticaba", "jabuticaba", "jackfruit", "jambul", "jamun",
+	"jocote", "jostaberry", "jujube", "kaki", "karonda", "keppel",
+	"kiwano", "kiwi", "korlan", "kumquat", "langsat", "lemon", "lime",
+	"lingonberry", "longan", "loquat", "lucuma", "lychee", "mamey",
+	"mammee", "mandarin", "mango", "mangosteen", "maqui", "marionberry",
+	"marula", "mayhaw", "medlar", "melon", "mirabelle", "miracle",
+	"mombin", "monstera", "mulberry", "muscadine", "nance", "nannyberry",
+	"naranjilla", "nectarine", "noni", "olive", "orange", "oroblanco",
+	"papaya", "passionfruit", "pawpaw", "peach", "pear", "pepino", "pequi",
+	"persimmon", "physalis", "pineapple", "pitanga", "pitaya", "plantain",
+	"plum", "pomegranate", "pomelo", "pulasan", "quandong", "quince",
+	"rambutan", "raspberry", "redcurrant", "riberry", "rollinia", "rowan",
+	"salak", "salal", "salmonberry", "santol", "sapodilla", "sapote",
+	"saskatoon", "satsuma", "serviceberry", "sloe", "sorb", "soursop",
+	"strawberry", "sugarapple", "surinam", "tamarillo", "tamarind",
+	"tangelo", "tangerine", "tayberry", "thimbleberry", "tomatillo",
+	"ugli", "ugni", "voavanga", "wampee", "watermelon", "whortleberry",
+	"wineberry", "wolfberry", "ximenia", "yangmei", "youngberry",
+	"yumberry", "yuzu", "ziziphus",
+}
+
+// Codenames the project has already had, from the current version and the
+// history
+func usedCodenames(v *version.GoVersion) map[string]bool {
+	used := map[string]bool{strings.ToLower(v.VersionString): true}
+	for _, entry := range v.History {
+		used[strings.ToLower(entry.VersionString)] = true
+	}
+	return used
+}
+
+// Picks a codename the project hasn't used. Once every word has been used,
+// words get a number, e.g. "apple-2".
+func randomCodename(used map[string]bool) string {
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for n := 1; ; n++ {
+		for _, i := range random.Perm(len(codenameWords)) {
+			codename := codenameWords[i]
+			if n > 1 {
+				codename = fmt.Sprintf("%s-%d", codename, n)
+			}
+			if !used[codename] {
+				return codename
+			}
+		}
+	}
+}
+
+// Sets a random codename, offering to pick again until one is accepted when
+// there's a terminal to ask on
+func setRandomCodename(v *version.GoVersion) *version.GoVersion {
+	used := usedCodenames(v)
+	for {
+		codename := randomCodename(used)
+		if !stdinIsTerminal() {
+			printInfo("Picked codename '%s'\n", codename)
+			return setCodename(v, codename)
+		}
+		if prompt.ConfirmWithDefault(fmt.Sprintf("Codename '%s', use it? (Y/n)", codename), true) {
+			return setCodename(v, codename)
+		}
+		used[codename] = true
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ANSI escape sequences for the colors gover uses
+const (
+	colorReset  string = "\x1b[0m"
+	colorRed    string = "\x1b[31m"
+	colorGreen  string = "\x1b[32m"
+	colorYellow string = "\x1b[33m"
+	colorCyan   string = "\x1b[36m"
+	colorDim    string = "\x1b[2m"
+)
+
+// Turns off colors even on a terminal, set by --no-color
+var noColor bool
+
+// Colors are only written to terminals, and never when NO_COLOR is set to
+// anything (https://no-color.org)
+func colorEnabled(w io.Writer) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+func colorize(w io.Writer, color string, text string) string {
+	if !colorEnabled(w) {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// Prints an error message to stderr, colored when that is a terminal. Any
+// details printed after it go to stderr as well, so that stdout only ever
+// holds the output that was asked for.
+func printError(format string, args ...interface{}) {
+	fmt.Fprint(os.Stderr, colorize(os.Stderr, colorRed, "ERROR:"), " ")
+	fmt.Fprintf(os.Stderr, format, args...)
+}
+
+// Prints a warning to stderr
+func printWarning(format string, args ...interface{}) {
+	fmt.Fprint(os.Stderr, colorize(os.Stderr, colorYellow, "WARNING:"), " ")
+	fmt.Fprintf(os.Stderr, format, args...)
+}
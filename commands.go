@@ -0,0 +1,1683 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/subtlepseudonym/go-prompt"
+	"github.com/subtlepseudonym/gover/pkg/buildinfo"
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// A gover subcommand. setup declares the command's flags on fs and returns
+// the function that runs it with the remaining positional arguments, once
+// every flag has been parsed. Help output and completion scripts are built
+// from these fields.
+type command struct {
+	name        string
+	usage       string
+	description string
+	// Printed after the description in the command's own help
+	details  string
+	examples []string
+	// Values the first argument can take, for completion
+	argValues []string
+	// Runs without looking for a version file
+	standalone bool
+	setup      func(fs *flag.FlagSet) func(args []string)
+}
+
+// Every command, the one with an empty name runs when none is given
+var commands = []command{
+	{
+		name:        "",
+		description: "Print the current version",
+		setup:       printCommand,
+	},
+	{
+		name:        "init",
+		usage:       "[--name <name>] [--version <version> | --from-git | --import <file> | --calver <pattern>] [--codename <codename> | --random-codename] [--build <n>] [--author <author>] [--description <text>] [--file-format <format>] [--yes]",
+		description: "Create a version file in the working directory",
+		details:     "Without a terminal, the answers are read from stdin one per line: project name,\nversion, version name, build number and confirmation. Fields given as flags\nare skipped, as is the version with --from-git or --calver, and blank lines take the default.\nThe optional author and description are only asked for on a terminal.\nWith --file-format text only the version is stored, in a VERSION file, and only the\nversion and confirmation are asked for.",
+		examples:    []string{"gover init", "gover init --name api --version 1.0.0 --codename apple --yes", "printf 'api\\n1.0.0\\napple\\n0\\ny\\n' | gover init", "gover init --from-git", "gover init --calver YYYY.0M.MICRO --timezone Europe/Berlin"},
+		setup:       initCommand,
+	},
+	{
+		name:        "check",
+		usage:       "[--quiet] [--schema]",
+		description: "Validate the version file",
+		details:     "Fields gover doesn't know, usually typos, are reported as problems here, while\nother commands only warn about them unless given --strict-fields. With --schema\nthe file is also validated against the JSON Schema printed by `gover schema`,\nwhich catches wrongly typed settings as well.",
+		setup:       checkCommand,
+	},
+	{
+		name:        "migrate",
+		usage:       "[--dry-run]",
+		description: "Rewrite the version file in the current schema",
+		details:     "Older version files are read as if they were already upgraded, migrate writes\nthe upgrade out and lists what changed.",
+		setup:       migrateCommand,
+	},
+	{
+		name:        "list",
+		usage:       "[--all] [--json]",
+		description: "List every project below the working directory",
+		setup:       listCommand,
+	},
+	{
+		name:        "major",
+		usage:       "[--commit [--push]] [--metadata <metadata>] [--random-codename] [--rewrite-imports]",
+		description: "Bump the major version",
+		details:     "From v2 on, the module path in go.mod gets the matching /vN suffix.",
+		examples:    []string{"gover major --commit", "gover major --commit --rewrite-imports"},
+		setup:       bumpCommand("major"),
+	},
+	{
+		name:        "minor",
+		usage:       "[--commit [--push]] [--metadata <metadata>] [--prompt-on-minor | --random-codename]",
+		description: "Bump the minor version",
+		examples:    []string{"gover minor --prompt-on-minor", "gover minor --random-codename"},
+		setup:       bumpCommand("minor"),
+	},
+	{
+		name:        "patch",
+		usage:       "[--commit [--push]] [--metadata <metadata>]",
+		description: "Bump the patch version",
+		examples:    []string{"gover patch", "gover patch --commit --message 'release {{.Version}}'"},
+		setup:       bumpCommand("patch"),
+	},
+	{
+		name:        "auto",
+		usage:       "[--commit [--push]] [--metadata <metadata>]",
+		description: "Bump the version called for by conventional commits since the last release",
+		examples:    []string{"gover auto --commit --push"},
+		setup:       bumpCommand("auto"),
+	},
+	{
+		name:        "bump",
+		usage:       "[--commit [--push]] [--metadata <metadata>] [<level>]",
+		description: "Bump to a level picked from a menu, or move a calendar versioned project to today's version",
+		details:     "Without a level the next patch, minor and major versions are offered in a menu,\nwhich needs a terminal. Projects initialized with --calver have no levels, for\nthem MICRO counts releases on the same date and restarts at 0 when the date\nchanges. Padded tokens like 0M give the same numbers as MM, since semver\nnumbers can't have leading zeroes.",
+		examples:    []string{"gover bump", "gover bump minor --commit"},
+		argValues:   version.Levels,
+		setup:       bumpCommand("bump"),
+	},
+	{
+		name:        "build",
+		usage:       "[<n>]",
+		description: "Increment the build number, or set it to n",
+		examples:    []string{"gover build", "gover build 42"},
+		setup:       buildCommand,
+	},
+	{
+		name:        "set-build",
+		usage:       "[--min] <n> | --from-ci",
+		description: "Set the build number to n",
+		details:     "With --min the build number is only changed when n is greater than the stored\none, so that pipelines racing each other can't move it backwards.",
+		examples:    []string{"gover set-build 1234", "gover set-build --min $BUILD_NUMBER", "gover set-build --from-ci"},
+		setup:       setBuildCommand,
+	},
+	{
+		name:        "pre",
+		usage:       "<label>",
+		description: "Set the prerelease label",
+		examples:    []string{"gover pre rc.1"},
+		setup:       preCommand,
+	},
+	{
+		name:        "rc",
+		usage:       "[--commit [--tag] [--push]] [<level>]",
+		description: "Cut the next release candidate",
+		details:     "A stable version is bumped by level, minor by default, and becomes rc.1. An\nexisting release candidate only has its number incremented, rc.2 to rc.3, and\nanother prerelease like beta.2 moves to rc.1 of the same version. Finish with\n`gover release`.",
+		examples:    []string{"gover rc", "gover rc major --commit --tag"},
+		argValues:   version.Levels,
+		setup:       bumpCommand("rc"),
+	},
+	{
+		name:        "release",
+		usage:       "[--commit [--tag] [--push]] [--clear-metadata] [--strict]",
+		description: "Finalize a prerelease into the stable version, e.g. 1.3.0-rc.3 to 1.3.0",
+		details:     "Build metadata is kept unless --clear-metadata is given. A version that isn't a\nprerelease is left alone, which is an error with --strict.",
+		examples:    []string{"gover release", "gover release --commit --tag --push"},
+		setup:       bumpCommand("release"),
+	},
+	{
+		name:        "set",
+		usage:       "[--force] <version>",
+		description: "Set the version",
+		examples:    []string{"gover set 1.4.0", "gover set 0.9.0 --force"},
+		setup:       setCommand,
+	},
+	{
+		name:        "get",
+		usage:       "<field>",
+		description: "Print a single field of the version file",
+		examples:    []string{"gover get version", "gover get all"},
+		argValues:   append(append([]string{}, getFields...), "all"),
+		setup:       getCommand,
+	},
+	{
+		name:        "env",
+		usage:       "[--prefix <prefix>]",
+		description: "Print the version as shell variable assignments",
+		examples:    []string{"eval \"$(gover env)\""},
+		setup:       envCommand,
+	},
+	{
+		name:        "export",
+		usage:       "[--env-file <path> [--merge] [--force]] [--prefix <prefix>]",
+		description: "Write the version to a .env file",
+		details:     "Without --env-file the lines are printed instead. Files outside of the\nrepository, or of the version file's directory outside of a repository, are\nonly written with --force.",
+		examples:    []string{"gover export --env-file .env --merge"},
+		setup:       exportCommand,
+	},
+	{
+		name:        "verify",
+		usage:       "[--remote]",
+		description: "Check that the tag for the current version exists and points at the right commit",
+		details:     "The tag has to exist, be HEAD or one of its ancestors and have the current\nversion in its version file. With --remote the upstream remote, or origin, has\nto have the same tag. The exit code is 1 when any check fails.",
+		examples:    []string{"gover verify --remote"},
+		setup:       verifyCommand,
+	},
+	{
+		name:        "inspect",
+		usage:       "<binary>",
+		description: "Compare the version embedded in a Go binary to the version file",
+		details:     "Reads the module version, VCS revision and any -X flags naming the ldflags\nvariables from the binary's build info. The exit code is 1 when the version,\ncodename or build disagree with the version file and 12 when the binary has\nno version info.",
+		examples:    []string{"gover inspect ./bin/app"},
+		setup:       inspectCommand,
+	},
+	{
+		name:        "next",
+		usage:       "[--quiet] <level>",
+		description: "Print the version a bump would produce, without changing anything",
+		examples:    []string{"gover next minor -q"},
+		argValues:   append(append([]string{}, version.Levels...), "build"),
+		setup:       nextCommand,
+	},
+	{
+		name:        "compare",
+		usage:       "<version>",
+		description: "Compare the current version to another, the exit code gives the result",
+		examples:    []string{"gover compare 1.4.0"},
+		setup:       compareCommand,
+	},
+	{
+		name:        "satisfies",
+		usage:       "<constraint>",
+		description: "Check the current version against a constraint, exiting 0 on a match and 1 otherwise",
+		details:     "Constraints are comma separated comparisons, with ~ and ^ ranges and x wildcards.\nA prerelease version only satisfies constraints that include a prerelease.",
+		examples:    []string{"gover satisfies '>=2.0.0, <3.0.0'", "gover satisfies ~1.2", "gover satisfies ^1.0.0"},
+		setup:       satisfiesCommand,
+	},
+	{
+		name:        "diff",
+		usage:       "[<git ref>]",
+		description: "Compare the version file with a committed revision, HEAD by default, exiting 1 if they differ",
+		examples:    []string{"gover diff", "gover diff origin/main"},
+		setup:       diffCommand,
+	},
+	{
+		name:        "compare-files",
+		usage:       "[--json] <a> <b>",
+		description: "Compare two version files field by field, exiting 1 if they differ",
+		details:     "The files can be in any of the formats gover reads, a ver.json can be compared\nwith a VERSION file or a ver.yaml. The version field notes which file is newer.\nA file that can't be read exits 2.",
+		examples:    []string{"gover compare-files ver.json ../release/ver.json", "gover compare-files --json ver.json ver.yaml"},
+		standalone:  true,
+		setup:       compareFilesCommand,
+	},
+	{
+		name:        "merge",
+		usage:       "<ancestor> <ours> <theirs> [<path>]",
+		description: "Resolve a version file conflict, as a git merge driver",
+		details:     "Run by git with `gover merge %O %A %B %P`, see install-merge-driver. The higher\nversion wins along with its codename, the build is the higher of the two and\nthe histories are combined. Other fields changed on both sides, like diverging\nproject names, leave the conflict for git to report, with exit code 1.",
+		examples:    []string{"gover install-merge-driver"},
+		standalone:  true,
+		setup:       mergeCommand,
+	},
+	{
+		name:        "install-merge-driver",
+		usage:       "[--dry-run]",
+		description: "Set up gover as the git merge driver for the version file",
+		details:     "Adds the driver to .git/config and a merge=gover attribute for the version file\nto .gitattributes. The config isn't shared with clones, so everyone who merges\nruns this once.",
+		setup:       installMergeDriverCommand,
+	},
+	{
+		name:        "install-hooks",
+		usage:       "[--hooks <hooks>] [--uninstall] [--dry-run]",
+		description: "Run gover from the repository's git hooks",
+		details:     "pre-commit increments the build number and stages the version file, pre-push\nruns `gover verify`. Existing hooks are kept, gover's commands are added in a\nmarked section at the end, which --uninstall removes. Hooks are written to\ncore.hooksPath when it's set.",
+		examples:    []string{"gover install-hooks", "gover install-hooks --hooks pre-commit", "gover install-hooks --uninstall"},
+		setup:       installHooksCommand,
+	},
+	{
+		name:        "history",
+		description: "Print the previous versions",
+		setup:       historyCommand,
+	},
+	{
+		name:        "undo",
+		usage:       "[--yes]",
+		description: "Restore the previous version",
+		setup:       undoCommand,
+	},
+	{
+		name:        "rollback",
+		usage:       "--to <version> [--yes] [--dry-run]",
+		description: "Restore the version, codename and build of an earlier version",
+		details:     "The state is taken from the newest history entry or backup with that version.\nThe entries in between are kept and the rollback is recorded as a new entry,\nso it can be undone like any other change.",
+		examples:    []string{"gover rollback --to 1.4.0 --dry-run"},
+		setup:       rollbackCommand,
+	},
+	{
+		name:        "generate",
+		usage:       "[--package <name>] [--output <path>]",
+		description: "Write a Go file with the version as constants",
+		examples:    []string{"//go:generate gover generate"},
+		setup:       generateCommand,
+	},
+	{
+		name:        "stamp",
+		usage:       "[--output <file>] [--check] <template>...",
+		description: "Render version values into template files",
+		details:     "Templates use text/template with the version file's fields, .Timestamp for when\nthe version file was last written and .SHA for HEAD's commit. foo.html.tmpl is\nrendered to foo.html unless --output names the file. --check writes nothing\nand exits 1 when a rendered file differs from the one on disk.",
+		examples:    []string{"gover stamp about.html.tmpl Info.plist.tmpl", "gover stamp --output nginx/version.conf version.conf.in", "gover stamp --check about.html.tmpl"},
+		setup:       stampCommand,
+	},
+	{
+		name:        "badge",
+		usage:       "[--label <text>] [--color <color>] [--output <file>] [--svg]",
+		description: "Write a version badge as shields.io endpoint JSON or an SVG",
+		details:     "Without --color, prereleases are orange and stable versions blue. --svg renders\nthe badge itself, for docs that can't reach shields.io, and takes the shields.io\ncolor names or a hex color.",
+		examples:    []string{"gover badge --output gh-pages/badge.json", "gover badge --svg --label release --output docs/version.svg"},
+		setup:       badgeCommand,
+	},
+	{
+		name:        "docker",
+		usage:       "labels | tags --image <name> [--flags]",
+		description: "Print OCI image labels or the image tags for the version",
+		details:     "labels prints --label arguments for the version, revision and created OCI\nannotations. tags prints the image tagged with the version, major.minor, major\nand latest, only the first for prereleases. Both print a single line that is\nsafe to use unquoted in command substitution.",
+		examples:    []string{"docker build $(gover docker labels) $(gover docker tags --image app --flags) .", "gover docker tags --image ghcr.io/org/app"},
+		argValues:   dockerOutputs,
+		setup:       dockerCommand,
+	},
+	{
+		name:        "ldflags",
+		usage:       "--pkg <import path> [--fields <fields>] [--vars <mapping>]",
+		description: "Print -ldflags that set version variables at build time",
+		examples:    []string{"go build -ldflags \"$(gover ldflags --pkg example.com/app/version)\""},
+		setup:       ldflagsCommand,
+	},
+	{
+		name:        "sync",
+		description: "Write the version into the files listed in syncFiles",
+		setup:       syncCommand,
+	},
+	{
+		name:        "readme",
+		usage:       "[--check]",
+		description: "Update the version badges and references in " + readmeFileName,
+		details:     "Static shields.io badges and mentions of the version before the current one are\nrewritten, code blocks never are. Set updateReadme to do this on every bump.",
+		examples:    []string{"gover readme", "gover readme --check"},
+		setup:       readmeCommand,
+	},
+	{
+		name:        "tag",
+		usage:       "[--force] [--sign] [--local-user <key id>] [--push] [--remote <name>] | --verify",
+		description: "Tag HEAD with the current version",
+		examples:    []string{"gover tag", "gover tag --sign --local-user 0xDEADBEEF", "gover tag --verify"},
+		setup:       tagCommand,
+	},
+	{
+		name:        "gh-release",
+		usage:       "[--title <template>] [--notes-file <file>] [--remote <name>] [--draft]",
+		description: "Create or update the GitHub release for the current version's tag",
+		details:     "The repository is taken from the remote's URL and the token from GITHUB_TOKEN,\nGITHUB_API_URL points it at GitHub Enterprise. The notes are the version's\nCHANGELOG.md section unless --notes-file is given, and prereleases are marked\nas such. An existing release for the tag is updated.",
+		examples:    []string{"gover gh-release", "gover gh-release --title '{{.ProjectName}} {{.Version}}' --notes-file notes.md"},
+		setup:       ghReleaseCommand,
+	},
+	{
+		name:        "rename",
+		usage:       "[<name>]",
+		description: "Change the project name",
+		examples:    []string{"gover rename \"My Project\""},
+		setup:       renameCommand,
+	},
+	{
+		name:        "codename",
+		usage:       "[<codename> | --random]",
+		description: "Change the version name",
+		examples:    []string{"gover codename durian", "gover codename --random"},
+		setup:       codenameCommand,
+	},
+	{
+		name:        "setmeta",
+		usage:       "<metadata>",
+		description: "Set the build metadata",
+		examples:    []string{"gover setmeta gitsha.abcdef"},
+		setup:       setmetaCommand,
+	},
+	{
+		name:        "set-field",
+		usage:       "<field> <value>",
+		description: "Set the author or description of the project",
+		details:     "Both are optional and only stored, an empty value removes the field.",
+		examples:    []string{"gover set-field author \"Jane <jane@example.com>\"", "gover set-field description \"\""},
+		argValues:   settableFields,
+		setup:       setFieldCommand,
+	},
+	{
+		name:        "field",
+		usage:       "set <key> <value> | get <key> | unset <key>",
+		description: "Manage custom fields stored in the version file",
+		details:     "Custom fields are kept in extras, untouched by bumps, and can be used in\ntemplates as {{.Extras.key}}. get exits 1 when the key isn't set, so that it\ncan be told apart from an empty value. Keys can't contain whitespace.",
+		examples:    []string{"gover field set channel beta", "gover field get channel", "gover field unset ticket"},
+		argValues:   fieldActions,
+		setup:       fieldCommand,
+	},
+	{
+		name:        "serve",
+		usage:       "[--addr <address>] [--once]",
+		description: "Serve the version as JSON over HTTP",
+		details:     "GET /version returns the version file as JSON, read again on every request.\nGET /healthz returns 200. SIGINT and SIGTERM shut the server down.",
+		examples:    []string{"gover serve --addr :8080", "gover serve --once"},
+		setup:       serveCommand,
+	},
+	{
+		name:        "schema",
+		usage:       "[--output <path>]",
+		description: "Print a JSON Schema for the version file",
+		details:     "The schema is generated from gover's own types and follows draft 2020-12. YAML\nand TOML version files have the same structure.",
+		examples:    []string{"gover schema --output ver.schema.json"},
+		standalone:  true,
+		setup:       schemaCommand,
+	},
+	{
+		name:        "version",
+		description: "Print gover's own version",
+		standalone:  true,
+		setup:       versionCommand,
+	},
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// Makes the global flags available on a command's FlagSet, sharing their
+// values, unless the command declares a flag of the same name itself. The
+// flags added are returned for the usage message.
+func addGlobalFlags(fs *flag.FlagSet) *flag.FlagSet {
+	global := flag.NewFlagSet("global", flag.ContinueOnError)
+	flag.VisitAll(func(f *flag.Flag) {
+		if fs.Lookup(f.Name) == nil {
+			fs.Var(f.Value, f.Name, f.Usage)
+			global.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+	return global
+}
+
+// Parses flags from anywhere in args rather than stopping at the first
+// positional argument, which flag.FlagSet.Parse does. Everything after "--"
+// is positional, and so are negative numbers, so that a command can reject
+// them with a better message than an unknown flag.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for len(args) > 0 {
+		if isNegativeNumber(args[0]) {
+			positional = append(positional, args[0])
+			args = args[1:]
+			continue
+		}
+		end := len(args)
+		for i, arg := range args {
+			if arg == "--" {
+				break
+			}
+			if isNegativeNumber(arg) {
+				end = i
+				break
+			}
+		}
+
+		fs.Parse(args[:end])
+		rest := fs.Args()
+		if consumed := end - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			positional = append(positional, rest...)
+			return append(positional, args[end:]...)
+		}
+		if len(rest) > 0 {
+			positional = append(positional, rest[0])
+			rest = rest[1:]
+		}
+		args = append(append([]string{}, rest...), args[end:]...)
+	}
+	return positional
+}
+
+func isNegativeNumber(arg string) bool {
+	_, err := strconv.Atoi(arg)
+	return err == nil && strings.HasPrefix(arg, "-")
+}
+
+// Loads the version file for a command that only reads it
+func loadForRead() *version.GoVersion {
+	v := loadVersionInfo()
+	prepareOutputFormat(v)
+	return v
+}
+
+// Locks and loads the version file for a command that changes it. With
+// --stdio there's no file to lock, and stdout is kept for the changed
+// version.
+func loadForUpdate() *version.GoVersion {
+	updatingVersionFile = true
+	if stdioMode {
+		startStdioOutput()
+		return loadForRead()
+	}
+	acquireLock()
+	return loadForRead()
+}
+
+// Writes the changes a command made to v, or describes them with --dry-run,
+// then prints the new version
+func saveChanges(before *version.GoVersion, v *version.GoVersion) {
+	changed := !sameVersion(before.Version, v.Version)
+	if changed {
+		v.RecordHistory(before)
+	}
+	if dryRun {
+		if printDryRun(before, v) {
+			printVersionInfo(v)
+		}
+		return
+	}
+	printToFile(v)
+	if changed && !stdioMode {
+		syncVersionFiles(v)
+	}
+	printVersionChange(before, v)
+}
+
+func printCommand(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		printVersionInfo(loadForRead())
+	}
+}
+
+func initCommand(fs *flag.FlagSet) func(args []string) {
+	var opts initOptions
+	fs.StringVar(&opts.name, "name", "", "project name")
+	fs.StringVar(&opts.version, "version", "", "starting version (default 0.1.0)")
+	fs.BoolVar(&opts.fromGit, "from-git", false, "start from the highest semver tag in the repository")
+	fs.StringVar(&opts.calver, "calver", "", "version by date with this pattern, e.g. YYYY.0M.MICRO")
+	fs.StringVar(&opts.timezone, "timezone", "", "time zone calendar versions are dated in, used with --calver (default UTC)")
+	fs.StringVar(&opts.importFrom, "import", "", "offer the version in this VERSION, package.json or pyproject.toml file as the default (default the first one found)")
+	fs.StringVar(&opts.codename, "codename", "", "version name")
+	fs.BoolVar(&opts.randomCodename, "random-codename", false, "pick a version name no version has had yet")
+	fs.StringVar(&opts.build, "build", "", "starting build number (default 0)")
+	fs.StringVar(&opts.author, "author", "", "project author, e.g. 'Jane <jane@example.com>'")
+	fs.StringVar(&opts.description, "description", "", "one line description of the project")
+	fs.BoolVar(&opts.yes, "yes", false, "skip the confirmation prompt")
+	fs.BoolVar(&opts.config, "config", false, "also write a starter "+version.ConfigFileNames[0]+" config file")
+	fs.BoolVar(&dryRun, "dry-run", dryRun, "show the new version file without creating it")
+	formatName := fs.String("file-format", "", fmt.Sprintf("version file format, one of: %s (default json)", strings.Join(version.FormatNames(), ", ")))
+
+	return func(args []string) {
+		format := version.FormatFor(versionFile)
+		if *formatName != "" {
+			var ok bool
+			format, ok = version.FormatNamed(*formatName)
+			if !ok {
+				printError("Unknown file format '%s', valid formats are: %s\n", *formatName, strings.Join(version.FormatNames(), ", "))
+				exit(exitUsage)
+			}
+		}
+
+		if explicitFile {
+			if version.FormatFor(versionFile).Name != format.Name {
+				printError("%s doesn't have a %s file extension\n", versionFile, format.Name)
+				exit(exitUsage)
+			}
+		} else {
+			// Any existing version file, in whatever format, means the project
+			// is already initialized. A VERSION file is only imported from
+			// unless it's the format being asked for.
+			cwd := filepath.Dir(versionFile)
+			existing := filesIn(cwd, version.FileNames)
+			if format.Name == version.Text.Name {
+				existing = versionFilesIn(cwd)
+			}
+			requireSingleVersionFile(cwd, existing)
+			if len(existing) > 0 {
+				versionFile = existing[0]
+			} else {
+				versionFile = filepath.Join(cwd, format.FileName())
+			}
+		}
+
+		// The tag prefix and looseVersions can come from a config file
+		// written before the version file
+		loadConfig()
+		settings = config.Settings
+
+		acquireLock()
+		v := initialize(opts)
+		if dryRun {
+			printInfo("Dry run, %s was not created\n", versionFile)
+			printVersionInfo(v)
+			return
+		}
+		printToFile(v)
+
+		if opts.config {
+			writeStarterConfig()
+		} else if !opts.yes && stdinIsTerminal() && len(filesIn(filepath.Dir(versionFile), version.ConfigFileNames)) == 0 {
+			if prompt.ConfirmWithDefault("Write a starter config file? (y/N)", false) {
+				writeStarterConfig()
+			}
+		}
+	}
+}
+
+// check has to cope with files that loadVersionInfo would refuse
+func checkCommand(fs *flag.FlagSet) func(args []string) {
+	quiet := fs.Bool("quiet", quietOutput, "don't print problems, only set the exit code")
+	schema := fs.Bool("schema", false, "also validate the file against gover's JSON Schema")
+
+	return func(args []string) {
+		problems := checkVersionFile(*schema)
+		if !*quiet {
+			for _, problem := range problems {
+				fmt.Printf("%s: %s\n", versionFile, problem)
+			}
+		}
+		if len(problems) > 0 {
+			exit(exitFailure)
+		}
+	}
+}
+
+func schemaCommand(fs *flag.FlagSet) func(args []string) {
+	output := fs.String("output", "", "write the schema to this file instead of printing it")
+
+	return func(args []string) {
+		schema, err := json.MarshalIndent(version.JSONSchema(), "", "  ")
+		if err != nil {
+			printError("Unable to marshal the schema\n")
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitFailure)
+		}
+		schema = append(schema, '\n')
+
+		if *output == "" {
+			stdout.Write(schema)
+			return
+		}
+		if err := os.WriteFile(*output, schema, 0644); err != nil {
+			printError("Unable to write %s\n", *output)
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitFailure)
+		}
+		printInfo("Wrote the version file schema to %s\n", *output)
+	}
+}
+
+func migrateCommand(fs *flag.FlagSet) func(args []string) {
+	fs.BoolVar(&dryRun, "dry-run", dryRun, "list the changes without writing them")
+
+	return func(args []string) {
+		v := loadForUpdate()
+		if textVersionFile() {
+			printInfo("%s only holds the version, there is nothing to migrate\n", filepath.Base(versionFile))
+			return
+		}
+		if len(schemaChanges) == 0 {
+			printInfo("%s is already at schema %d\n", versionFile, version.CurrentSchema)
+			return
+		}
+
+		for _, change := range schemaChanges {
+			printInfo("  %s\n", change)
+		}
+		if dryRun {
+			printInfo("Dry run, %s was not migrated\n", versionFile)
+			return
+		}
+		printToFile(v)
+		printInfo("Migrated %s to schema %d\n", versionFile, version.CurrentSchema)
+	}
+}
+
+// list reads every version file in the tree rather than just this one
+func listCommand(fs *flag.FlagSet) func(args []string) {
+	all := fs.Bool("all", false, "also search hidden, vendor and node_modules directories")
+	fs.BoolVar(&jsonOutput, "json", jsonOutput, "print the projects as a JSON array")
+
+	return func(args []string) {
+		listProjects(*all)
+	}
+}
+
+func bumpCommand(level string) func(fs *flag.FlagSet) func(args []string) {
+	return func(fs *flag.FlagSet) func(args []string) {
+		metadata := fs.String("metadata", "", "build metadata to attach to the new version")
+		commit := fs.Bool("commit", false, "commit the version file after bumping")
+		message := fs.String("message", defaultCommitMessage, "commit message template, used with --commit")
+		allowStaged := fs.Bool("allow-staged", false, "include already staged files in the bump commit")
+		force := fs.Bool("force", false, "bump even when tracked files have uncommitted changes")
+		fs.StringVar(&outputFormat, "format", outputFormat, "text/template used to print the new version")
+		fs.BoolVar(&jsonOutput, "json", jsonOutput, "print the previous and new versions as JSON")
+		keepBuild := fs.Bool("keep-build", false, "leave the build number as it is, overriding resetBuildOnBump and bumpBuildOnVersionBump")
+		bumpBuild := fs.Bool("bump-build", false, "increment the build number along with the version")
+		buildFromCI := fs.Bool("build-from-ci", false, "set the build number from the CI job, on "+ciProviderNames())
+		noChangelog := fs.Bool("no-changelog", false, "don't add the new version to "+changelogFileName)
+		noHooks := fs.Bool("no-hooks", false, "skip the preBump and postBump hooks")
+		strictNotify := fs.Bool("strict-notify", false, "exit 1 when a notification webhook fails, after writing the bump")
+		tag := fs.Bool("tag", false, "tag the bump commit with the new version, used with --commit")
+		push := fs.Bool("push", false, "push the bump commit, and the tag with --tag, used with --commit")
+		remote := fs.String("remote", "", "remote to push to instead of the branch's upstream")
+		rewriteImports := fs.Bool("rewrite-imports", false, "rewrite the module's own imports when go.mod moves to a new major version")
+		fs.BoolVar(&dryRun, "dry-run", dryRun, "show the new version without writing it")
+		var yes bool
+		fs.BoolVar(&yes, "yes", false, "don't ask for confirmation when confirmBumps or confirmMajor is set")
+		fs.BoolVar(&yes, "y", false, "don't ask for confirmation (shorthand)")
+		fs.BoolVar(&quietOutput, "quiet", quietOutput, "print only the new version")
+		fs.BoolVar(&quietOutput, "q", quietOutput, "print only the new version (shorthand)")
+		fs.BoolVar(&showPrevious, "show-previous", false, "print the previous version before the new one, used with --quiet")
+		var promptOnMinor, randomCodename, strict, clearMetadata bool
+		if level == "release" {
+			fs.BoolVar(&strict, "strict", false, "exit 1 when the version isn't a prerelease")
+			fs.BoolVar(&clearMetadata, "clear-metadata", false, "drop the build metadata as well")
+		}
+		if level == "minor" {
+			fs.BoolVar(&promptOnMinor, "prompt-on-minor", false, "ask for a new codename")
+		}
+		if level == "major" || level == "minor" {
+			fs.BoolVar(&randomCodename, "random-codename", false, "pick a codename no version has had yet")
+		}
+
+		return func(args []string) {
+			// The config file is only read once flags are parsed, so the
+			// flag default can't come from it
+			messageGiven := false
+			fs.Visit(func(f *flag.Flag) {
+				messageGiven = messageGiven || f.Name == "message"
+			})
+			if !messageGiven && config.CommitMessage != "" {
+				*message = config.CommitMessage
+			}
+
+			if showPrevious && !quietOutput {
+				printError("--show-previous changes the --quiet output, use it with --quiet\n")
+				exit(exitUsage)
+			}
+			if promptOnMinor && randomCodename {
+				printError("--prompt-on-minor can't be combined with --random-codename\n")
+				exit(exitUsage)
+			}
+			if stdioMode && (*commit || *push || *tag) {
+				printError("--stdio doesn't write the version file, there's nothing to commit, tag or push\n")
+				exit(exitUsage)
+			}
+			if clearMetadata && *metadata != "" {
+				printError("--clear-metadata can't be combined with --metadata\n")
+				exit(exitUsage)
+			}
+
+			v := loadForUpdate()
+			requireVersion(v, "bump")
+			before := v.Clone()
+			previous := v.Version
+
+			switch {
+			case level == "bump" && v.CalVer != nil && len(args) > 0:
+				printError("%s is calendar versioned (%s), bump takes no level\n", versionFile, v.CalVer.Pattern)
+				exit(exitUsage)
+			case level == "bump" && v.CalVer == nil && len(args) > 0 && !knownLevel(args[0]):
+				printError("Unknown level '%s', valid levels are: %s\n", args[0], strings.Join(version.Levels, ", "))
+				exit(exitUsage)
+			case level == "bump" && v.CalVer == nil && len(args) == 0 && !stdinIsTerminal():
+				printError("Missing level, valid levels are: %s\n", strings.Join(version.Levels, ", "))
+				fmt.Fprintln(os.Stderr, "Usage: gover bump <level>, or run it in a terminal to pick one")
+				exit(exitUsage)
+			case level == "rc" && len(args) > 0 && !knownLevel(args[0]):
+				printError("Unknown level '%s', valid levels are: %s\n", args[0], strings.Join(version.Levels, ", "))
+				exit(exitUsage)
+			case level != "bump" && level != "release" && v.CalVer != nil:
+				printError("%s is calendar versioned (%s), it has no %s level\n", versionFile, v.CalVer.Pattern, level)
+				fmt.Fprintln(os.Stderr, "Run `gover bump` to move to today's version")
+				exit(exitUsage)
+			}
+			if level == "release" && v.Version.Prerelease() == "" {
+				if strict {
+					printError("%s is not a prerelease, there is nothing to release\n", displayVersion(v.Version))
+					exit(exitFailure)
+				}
+				printInfo("%s is not a prerelease, there is nothing to release\n", displayVersion(v.Version))
+				return
+			}
+
+			if !*force && !settings.AllowDirty {
+				requireCleanTree(*commit && *allowStaged)
+			}
+			if *push && !*commit {
+				printError("--push needs --commit, there is nothing to push otherwise\n")
+				exit(exitUsage)
+			}
+			if *tag && !*commit {
+				printError("--tag needs --commit, the tag would point at a commit without the new version\n")
+				exit(exitUsage)
+			}
+			if *commit {
+				requireGitRepo()
+				if !*allowStaged {
+					requireNothingStaged()
+				}
+			}
+			if *push && *remote == "" {
+				requireUpstream()
+			}
+
+			if *buildFromCI && (*keepBuild || *bumpBuild) {
+				printError("--build-from-ci can't be combined with --keep-build or --bump-build\n")
+				exit(exitUsage)
+			}
+			fromCI := (*buildFromCI || settings.BuildFromCI) && !*keepBuild
+			if fromCI && settings.BuildSource == version.BuildSourceGitCount {
+				printError("The build number is the git commit count (buildSource git-count), it can't be taken from CI\n")
+				exit(exitUsage)
+			}
+			var ciBuildNumber int
+			if fromCI {
+				ciBuildNumber = ciBuild()
+			}
+
+			resetBuild := settings.ResetBuildOnBump && !*keepBuild && !fromCI
+			incrementBuild := (settings.BumpBuildOnVersionBump || *bumpBuild) && !*keepBuild && !fromCI
+			if resetBuild && incrementBuild {
+				printError("resetBuildOnBump can't be combined with incrementing the build, use --keep-build to leave it as it is\n")
+				exit(exitUsage)
+			}
+
+			level := level
+			switch {
+			case level == "auto":
+				level = autoLevel(v)
+			case level == "bump" && v.CalVer == nil && len(args) > 0:
+				level = args[0]
+			case level == "bump" && v.CalVer == nil:
+				level = promptBumpLevel(v)
+			}
+			var err error
+			switch level {
+			case "bump":
+				err = v.BumpCalVer(now())
+			case "release":
+				err = v.Release(clearMetadata)
+			case "rc":
+				candidateLevel := "minor"
+				if len(args) > 0 {
+					candidateLevel = args[0]
+				}
+				if len(args) > 0 && previous.Prerelease() != "" {
+					printWarning("%s is already a prerelease, the %s level is ignored\n", displayVersion(previous), candidateLevel)
+				}
+				err = v.NextCandidate(candidateLevel)
+			default:
+				err = v.Bump(level)
+			}
+			if err != nil && level == "bump" {
+				printError("Unable to bump the calendar version\n")
+				fmt.Fprintln(os.Stderr, err)
+				exit(exitFailure)
+			}
+			if err != nil && level == "rc" {
+				printError("Unable to move %s to the next release candidate\n", displayVersion(previous))
+				fmt.Fprintln(os.Stderr, err)
+				exit(exitFailure)
+			}
+			if err != nil && level == "release" {
+				printError("Unable to release %s\n", displayVersion(previous))
+				fmt.Fprintln(os.Stderr, err)
+				exit(exitFailure)
+			}
+			if err != nil {
+				printError("Unable to bump %s version\n", level)
+				fmt.Fprintln(os.Stderr, err)
+				exit(exitFailure)
+			}
+			if resetBuild && v.Build != 0 {
+				printInfo("Build reset %d -> 0\n", v.Build)
+				v.Build = 0
+			}
+			if incrementBuild {
+				printInfo("Build %d -> %d\n", v.Build, v.Build+1)
+				v.IncrementBuild()
+			}
+			if fromCI && v.Build != ciBuildNumber {
+				printInfo("Build %d -> %d from CI\n", v.Build, ciBuildNumber)
+				v.Build = ciBuildNumber
+			}
+			if *metadata != "" {
+				v = setMetadata(v, *metadata)
+			}
+			if randomCodename {
+				v = setRandomCodename(v)
+			} else if promptOnMinor {
+				v = promptCodename(v)
+			}
+			recordCommit(v)
+
+			v.RecordHistory(before)
+			if *tag && gitTagExists(tagName(v)) {
+				printError("Tag %s already exists\n", tagName(v))
+				exit(exitTagExists)
+			}
+			if dryRun {
+				if printDryRun(before, v) {
+					printBumpInfo(before, v)
+				}
+				return
+			}
+
+			major := before.Version.Major() != v.Version.Major()
+			if !yes && (settings.ConfirmBumps || (settings.ConfirmMajor && major)) {
+				confirmBump(before, v)
+			}
+
+			var commitMessage string
+			if *commit {
+				commitMessage = renderCommitMessage(v, *message)
+			}
+
+			hooks := settings.Hooks
+			if *noHooks || hooks == nil {
+				hooks = &version.Hooks{}
+			}
+			if err := runHooks("preBump", hooks.PreBump, previous, v.Version); err != nil {
+				printError("Pre bump hook failed, the version was not changed\n")
+				fmt.Fprintln(os.Stderr, err)
+				exit(exitFailure)
+			}
+
+			printToFile(v)
+			var synced []string
+			if !stdioMode {
+				synced = syncVersionFiles(v)
+				synced = append(synced, updateGoMod(before, v, *rewriteImports)...)
+				if settings.UpdateReadme {
+					if path := updateReadme(before, v); path != "" {
+						synced = append(synced, path)
+					}
+				}
+				if !*noChangelog && (settings.Changelog == nil || *settings.Changelog) {
+					if path := updateChangelog(before, v); path != "" {
+						synced = append(synced, path)
+					}
+				}
+			}
+			var tagged string
+			if *commit {
+				commitVersionFile(commitMessage, synced...)
+				if *tag {
+					tagged = createTag(v, false, tagSigning{sign: config.SignTags})
+				}
+			}
+			if err := runHooks("postBump", hooks.PostBump, previous, v.Version); err != nil {
+				printError("Post bump hook failed, %s was already written\n", displayVersion(v.Version))
+				fmt.Fprintln(os.Stderr, err)
+				exit(exitFailure)
+			}
+			if *push {
+				pushCommit(*remote)
+				if tagged != "" {
+					pushTag(tagged, *remote, false)
+				}
+			}
+			notifyBump(before, v, *strictNotify)
+			printBumpInfo(before, v)
+		}
+	}
+}
+
+// Exits unless the build number is stored in the version file, where build
+// and set-build change it. A text file would leave the change unsaved.
+func requireStoredBuild() {
+	if settings.BuildSource == version.BuildSourceGitCount {
+		printError("The build number is the git commit count (buildSource git-count), it can't be set by hand\n")
+		exit(exitUsage)
+	}
+	if textVersionFile() {
+		printError("%s only stores the version, there is no build number to change\n", filepath.Base(versionFile))
+		fmt.Fprintln(os.Stderr, "Set buildSource to git-count to take the build from git, or use another format to store it")
+		exit(exitFailure)
+	}
+}
+
+func buildCommand(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		v := loadForUpdate()
+		before := v.Clone()
+		requireStoredBuild()
+
+		if len(args) < 1 {
+			v.IncrementBuild()
+		} else {
+			build, err := strconv.Atoi(args[0])
+			if err == nil {
+				err = v.SetBuild(build)
+			}
+			if err != nil {
+				printError("Build number must be a non-negative integer, got '%s'\n", args[0])
+				exit(exitUsage)
+			}
+		}
+		saveChanges(before, v)
+	}
+}
+
+func setBuildCommand(fs *flag.FlagSet) func(args []string) {
+	min := fs.Bool("min", false, "only change the build number when n is greater than the stored one")
+	fromCI := fs.Bool("from-ci", false, "take n from the CI job, on "+ciProviderNames())
+
+	return func(args []string) {
+		var build int
+		switch {
+		case *fromCI && len(args) > 0:
+			printError("--from-ci takes the build number from the environment, don't pass one as well\n")
+			exit(exitUsage)
+		case *fromCI:
+		case len(args) < 1:
+			printError("Missing build number, e.g. `gover set-build 42`\n")
+			exit(exitUsage)
+		default:
+			var err error
+			build, err = strconv.Atoi(args[0])
+			if err != nil || build < 0 {
+				printError("Build number must be a non-negative integer, got '%s'\n", args[0])
+				exit(exitUsage)
+			}
+		}
+
+		v := loadForUpdate()
+		before := v.Clone()
+		requireStoredBuild()
+		if *fromCI {
+			build = ciBuild()
+		}
+		if *min && build <= v.Build {
+			printInfo("Build %d is not greater than the stored build %d, leaving it as it is\n", build, v.Build)
+			return
+		}
+
+		printInfo("Build %d -> %d\n", v.Build, build)
+		v.SetBuild(build)
+		saveChanges(before, v)
+	}
+}
+
+func preCommand(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		if len(args) < 1 {
+			printError("Missing prerelease label, e.g. `gover pre rc.1`\n")
+			exit(exitUsage)
+		}
+
+		v := loadForUpdate()
+		before := v.Clone()
+		if err := v.SetPrerelease(args[0]); err != nil {
+			printError("'%s' is not a valid semver prerelease label\n", args[0])
+			exit(exitUsage)
+		}
+		saveChanges(before, v)
+	}
+}
+
+func setCommand(fs *flag.FlagSet) func(args []string) {
+	force := fs.Bool("force", false, "allow setting a version lower than the current one")
+	fs.BoolVar(&dryRun, "dry-run", dryRun, "show the new version without writing it")
+
+	return func(args []string) {
+		if len(args) < 1 {
+			printError("Missing version, e.g. `gover set 1.4.0`\n")
+			exit(exitUsage)
+		}
+		// looseVersions can be set in the version file, so it's loaded first
+		v := loadForUpdate()
+		before := v.Clone()
+		newVersion, err := parseVersionInput(args[0])
+		if err != nil {
+			printError("Unable to parse version '%s'\n", args[0])
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitUsage)
+		}
+		if v.Version != nil && newVersion.LessThan(v.Version) && !*force {
+			printError("%s is lower than the current version %s, use --force to set it anyway\n", displayVersion(newVersion), displayVersion(v.Version))
+			exit(exitFailure)
+		}
+
+		printInfo("Setting version %s -> %s\n", displayVersion(v.Version), displayVersion(newVersion))
+		v.Version = newVersion
+		saveChanges(before, v)
+	}
+}
+
+func getCommand(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		if len(args) < 1 {
+			printError("Missing field, valid fields are: %s, all\n", strings.Join(getFields, ", "))
+			exit(exitUsage)
+		}
+		printField(loadForRead(), args[0])
+	}
+}
+
+func envCommand(fs *flag.FlagSet) func(args []string) {
+	prefix := fs.String("prefix", "GOVER_", "prefix for the exported variable names")
+
+	return func(args []string) {
+		if !envPrefixPattern.MatchString(*prefix) {
+			printError("'%s' is not a valid environment variable prefix\n", *prefix)
+			exit(exitUsage)
+		}
+		printEnv(loadForRead(), *prefix)
+	}
+}
+
+func exportCommand(fs *flag.FlagSet) func(args []string) {
+	envFile := fs.String("env-file", "", "write to this file, creating or replacing it")
+	merge := fs.Bool("merge", false, "only replace the gover keys in an existing --env-file")
+	force := fs.Bool("force", false, "write --env-file even when it's outside of the repository")
+	prefix := fs.String("prefix", "GOVER_", "prefix for the exported variable names")
+
+	return func(args []string) {
+		if !envPrefixPattern.MatchString(*prefix) {
+			printError("'%s' is not a valid environment variable prefix\n", *prefix)
+			exit(exitUsage)
+		}
+		v := loadForRead()
+		if *envFile == "" {
+			if *merge {
+				printError("--merge needs --env-file\n")
+				exit(exitUsage)
+			}
+			_, lines := envFileLines(v, *prefix)
+			for _, line := range lines {
+				fmt.Fprintln(stdout, line)
+			}
+			return
+		}
+
+		if !*force {
+			requireInsideProject(*envFile)
+		}
+		writeEnvFile(v, *envFile, *prefix, *merge)
+	}
+}
+
+func verifyCommand(fs *flag.FlagSet) func(args []string) {
+	remote := fs.Bool("remote", false, "also check that the tag was pushed")
+
+	return func(args []string) {
+		verifyRelease(loadForRead(), *remote)
+	}
+}
+
+func inspectCommand(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		if len(args) < 1 {
+			printError("Missing binary to inspect, e.g. `gover inspect ./bin/app`\n")
+			exit(exitUsage)
+		}
+		inspectBinary(loadForRead(), args[0])
+	}
+}
+
+func nextCommand(fs *flag.FlagSet) func(args []string) {
+	fs.BoolVar(&quietOutput, "quiet", quietOutput, "print only the next version")
+	fs.BoolVar(&quietOutput, "q", quietOutput, "print only the next version (shorthand)")
+
+	return func(args []string) {
+		levels := append(append([]string{}, version.Levels...), "build")
+		if len(args) < 1 {
+			printError("Missing level, valid levels are: %s\n", strings.Join(levels, ", "))
+			exit(exitUsage)
+		}
+
+		// Only ever applied in memory, the version file is left as it is
+		v := loadForRead()
+		level := args[0]
+		if v.CalVer != nil && level != "build" {
+			if level != "bump" {
+				printError("%s is calendar versioned (%s), valid levels are: bump, build\n", versionFile, v.CalVer.Pattern)
+				exit(exitUsage)
+			}
+			if err := v.BumpCalVer(now()); err != nil {
+				printError("Unable to work out the next calendar version\n")
+				fmt.Fprintln(os.Stderr, err)
+				exit(exitFailure)
+			}
+		} else if level == "build" {
+			v.IncrementBuild()
+		} else if err := v.Bump(level); err != nil {
+			printError("Unknown level '%s', valid levels are: %s\n", level, strings.Join(levels, ", "))
+			exit(exitUsage)
+		}
+		printVersionInfo(v)
+	}
+}
+
+func compareCommand(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		if len(args) < 1 {
+			printError("Missing version to compare against, e.g. `gover compare 1.4.0`\n")
+			exit(exitUsage)
+		}
+		// Strict parsing is for versions gover stores, one it only compares
+		// against is read leniently so that `gover compare 1.0` keeps working
+		other, _, err := version.CoerceVersion(args[0])
+		if err != nil {
+			printError("Unable to parse version '%s'\n", args[0])
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitUsage)
+		}
+
+		// Describes the current version relative to the argument, following
+		// semver precedence (prereleases sort before their release)
+		v := loadForRead()
+		requireVersion(v, "compare")
+		switch v.Version.Compare(other) {
+		case -1:
+			fmt.Fprintln(stdout, "older")
+			exit(exitCompareOlder)
+		case 1:
+			fmt.Fprintln(stdout, "newer")
+			exit(exitCompareNewer)
+		}
+		fmt.Fprintln(stdout, "equal")
+		exit(exitCompareEqual)
+	}
+}
+
+func satisfiesCommand(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		if len(args) < 1 {
+			printError("Missing constraint, e.g. `gover satisfies '>=2.0.0, <3.0.0'`\n")
+			exit(exitUsage)
+		}
+		constraint, err := semver.NewConstraint(args[0])
+		if err != nil {
+			printError("Unable to parse constraint '%s'\n", args[0])
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitUsage)
+		}
+
+		v := loadForRead()
+		requireVersion(v, "check the constraint against")
+		// The note goes to stderr so that stdout is only ever true or false
+		if v.Version.Prerelease() != "" {
+			fmt.Fprintf(os.Stderr, "%s is a prerelease, which only satisfies constraints that include a prerelease\n", displayVersion(v.Version))
+		}
+		ok, reasons := constraint.Validate(v.Version)
+		if !ok {
+			for _, reason := range reasons {
+				logVerbose("%s", reason)
+			}
+			fmt.Fprintln(stdout, "false")
+			exit(exitFailure)
+		}
+		fmt.Fprintln(stdout, "true")
+	}
+}
+
+func diffCommand(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		ref := "HEAD"
+		if len(args) > 0 {
+			ref = args[0]
+		}
+		diffVersion(loadForRead(), ref)
+	}
+}
+
+func compareFilesCommand(fs *flag.FlagSet) func(args []string) {
+	fs.BoolVar(&jsonOutput, "json", jsonOutput, "print the differences as JSON")
+
+	return func(args []string) {
+		if len(args) != 2 {
+			printError("compare-files takes two version files, usage: gover compare-files <a> <b>\n")
+			exit(exitUsage)
+		}
+		compareFiles(args[0], args[1])
+	}
+}
+
+func mergeCommand(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		if len(args) < 3 || len(args) > 4 {
+			printError("merge takes the ancestor, ours and theirs, usage: gover merge %%O %%A %%B %%P\n")
+			exit(exitUsage)
+		}
+		path := ""
+		if len(args) == 4 {
+			path = args[3]
+		}
+		mergeVersionFiles(args[0], args[1], args[2], path)
+	}
+}
+
+func installMergeDriverCommand(fs *flag.FlagSet) func(args []string) {
+	fs.BoolVar(&dryRun, "dry-run", dryRun, "show the changes without making them")
+
+	return func(args []string) {
+		installMergeDriver()
+	}
+}
+
+func installHooksCommand(fs *flag.FlagSet) func(args []string) {
+	hooks := fs.String("hooks", strings.Join(gitHookNames, ","), "comma separated hooks to install or remove, from: "+strings.Join(gitHookNames, ", "))
+	uninstall := fs.Bool("uninstall", false, "remove gover from the hooks instead")
+	fs.BoolVar(&dryRun, "dry-run", dryRun, "show the changes without making them")
+
+	return func(args []string) {
+		selected := selectGitHooks(*hooks)
+		loadForRead()
+		for _, hook := range selected {
+			if hook == "pre-commit" && !*uninstall && settings.BuildSource == version.BuildSourceGitCount {
+				printError("The build number is the git commit count (buildSource git-count), the pre-commit hook can't set it\n")
+				exit(exitUsage)
+			}
+		}
+		installGitHooks(selected, *uninstall)
+	}
+}
+
+func historyCommand(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		printHistory(loadForRead())
+	}
+}
+
+func undoCommand(fs *flag.FlagSet) func(args []string) {
+	yes := fs.Bool("yes", false, "skip the confirmation prompt")
+	fs.BoolVar(&dryRun, "dry-run", dryRun, "show the restored version without writing it")
+
+	return func(args []string) {
+		v := loadForUpdate()
+		requireVersion(v, "undo")
+		before := v.Clone()
+
+		v = undo(v, *yes || dryRun)
+		if dryRun {
+			if printDryRun(before, v) {
+				printVersionInfo(v)
+			}
+			return
+		}
+		printToFile(v)
+		if !sameVersion(before.Version, v.Version) {
+			syncVersionFiles(v)
+		}
+		printVersionChange(before, v)
+	}
+}
+
+func rollbackCommand(fs *flag.FlagSet) func(args []string) {
+	to := fs.String("to", "", "version to roll back to")
+	yes := fs.Bool("yes", false, "skip the confirmation prompt")
+	fs.BoolVar(&dryRun, "dry-run", dryRun, "show the restored version without writing it")
+
+	return func(args []string) {
+		if *to == "" {
+			printError("Missing version to roll back to, e.g. `gover rollback --to 1.4.0`\n")
+			exit(exitUsage)
+		}
+		// Read leniently like compare, the target has to match a recorded
+		// version anyway
+		target, _, err := version.CoerceVersion(*to)
+		if err != nil {
+			printError("Unable to parse version '%s'\n", *to)
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitUsage)
+		}
+
+		v := loadForUpdate()
+		requireVersion(v, "roll back")
+		if v.Version.String() == target.String() {
+			printInfo("%s is already at %s\n", versionFile, displayVersion(v.Version))
+			return
+		}
+		before := v.Clone()
+		saveChanges(before, rollback(v, target, *yes || dryRun))
+	}
+}
+
+func generateCommand(fs *flag.FlagSet) func(args []string) {
+	// go generate runs in the package directory and sets GOPACKAGE, so a
+	// bare //go:generate directive needs no flags
+	defaultPackage := os.Getenv("GOPACKAGE")
+	if defaultPackage == "" {
+		defaultPackage = "main"
+	}
+	pkg := fs.String("package", defaultPackage, "package name for the generated file")
+	output := fs.String("output", defaultGenerateOutput, "path of the generated file")
+	fs.BoolVar(&dryRun, "dry-run", dryRun, "print the generated file without writing it")
+
+	return func(args []string) {
+		generate(loadForRead(), *pkg, *output)
+	}
+}
+
+func stampCommand(fs *flag.FlagSet) func(args []string) {
+	output := fs.String("output", "", "write the rendered file here, with a single template")
+	check := fs.Bool("check", false, "exit 1 if a rendered file differs from the one on disk, writing nothing")
+	fs.BoolVar(&dryRun, "dry-run", dryRun, "print the rendered files without writing them")
+
+	return func(args []string) {
+		stampFiles(loadForRead(), args, *output, *check)
+	}
+}
+
+func badgeCommand(fs *flag.FlagSet) func(args []string) {
+	label := fs.String("label", "version", "text on the left of the badge")
+	color := fs.String("color", "", "color of the version, orange for prereleases and blue otherwise by default")
+	output := fs.String("output", "", "write the badge to this file instead of stdout")
+	svg := fs.Bool("svg", false, "render an SVG badge instead of shields.io endpoint JSON")
+
+	return func(args []string) {
+		writeBadge(loadForRead(), *label, *color, *output, *svg)
+	}
+}
+
+func dockerCommand(fs *flag.FlagSet) func(args []string) {
+	image := fs.String("image", "", "image name to tag, used with tags")
+	tagFlags := fs.Bool("flags", false, "print the tags as --tag arguments for docker build")
+
+	return func(args []string) {
+		if len(args) < 1 {
+			printError("Missing output, valid outputs are: %s\n", strings.Join(dockerOutputs, ", "))
+			exit(exitUsage)
+		}
+		printDocker(loadForRead(), args[0], *image, *tagFlags)
+	}
+}
+
+func ghReleaseCommand(fs *flag.FlagSet) func(args []string) {
+	title := fs.String("title", "", "text/template for the release title, the tag name by default")
+	notesFile := fs.String("notes-file", "", "take the release notes from this file instead of CHANGELOG.md")
+	remote := fs.String("remote", "", "remote pointing at the GitHub repository, the branch's upstream or origin by default")
+	draft := fs.Bool("draft", false, "create the release as a draft")
+	fs.BoolVar(&dryRun, "dry-run", dryRun, "print the release without creating it")
+
+	return func(args []string) {
+		publishGitHubRelease(loadForRead(), *title, *notesFile, *remote, *draft)
+	}
+}
+
+func ldflagsCommand(fs *flag.FlagSet) func(args []string) {
+	pkg := fs.String("pkg", "", "import path of the package holding the variables")
+	fields := fs.String("fields", defaultLdflagsFields, fmt.Sprintf("comma separated fields to set, from: %s", strings.Join(getFields, ", ")))
+	vars := fs.String("vars", "", "comma separated field=Variable names, e.g. codename=Codename")
+
+	return func(args []string) {
+		fmt.Fprintln(stdout, ldflags(loadForRead(), *pkg, *fields, *vars))
+	}
+}
+
+func syncCommand(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		v := loadForRead()
+		if len(settings.SyncFiles) == 0 {
+			printInfo("Nothing to sync, add syncFiles to %s or the config file\n", versionFile)
+			return
+		}
+		for _, file := range syncVersionFiles(v) {
+			printInfo("Updated %s\n", file)
+		}
+	}
+}
+
+func readmeCommand(fs *flag.FlagSet) func(args []string) {
+	check := fs.Bool("check", false, "report out of date lines without changing them, exiting 1 if there are any")
+
+	return func(args []string) {
+		v := loadForRead()
+		_, changes := readmeChanges(v, staleReadmeVersion(v), !*check)
+		if len(changes) == 0 {
+			printInfo("%s is up to date with %s\n", readmeFileName, displayVersion(v.Version))
+			return
+		}
+		for _, change := range changes {
+			if *check {
+				fmt.Printf("%s:%d: out of date\n  - %s\n  + %s\n", readmeFileName, change.number, change.old, change.new)
+			} else {
+				printInfo("Updated %s:%d\n  - %s\n  + %s\n", readmeFileName, change.number, change.old, change.new)
+			}
+		}
+		if *check && len(changes) > 0 {
+			exit(exitFailure)
+		}
+	}
+}
+
+func tagCommand(fs *flag.FlagSet) func(args []string) {
+	force := fs.Bool("force", false, "move the tag if it already exists")
+	sign := fs.Bool("sign", false, "sign the tag with GPG (default from signTags in the config file)")
+	localUser := fs.String("local-user", "", "GPG key to sign the tag with, implies --sign")
+	verify := fs.Bool("verify", false, "check the signature on the existing tag instead of creating one")
+	push := fs.Bool("push", false, "push the tag after creating it")
+	remote := fs.String("remote", "", "remote to push the tag to (default the branch's upstream remote, or origin)")
+
+	return func(args []string) {
+		v := loadForRead()
+		if *verify {
+			printInfo("Tag %s has a good signature\n", verifyTag(v))
+			return
+		}
+
+		signGiven := false
+		fs.Visit(func(f *flag.Flag) {
+			signGiven = signGiven || f.Name == "sign"
+		})
+		signing := tagSigning{
+			sign:     *sign || *localUser != "" || (!signGiven && config.SignTags),
+			explicit: signGiven || *localUser != "",
+			keyID:    *localUser,
+		}
+		name := createTag(v, *force, signing)
+		if *push {
+			pushTag(name, *remote, *force)
+		}
+		fmt.Fprintln(stdout, name)
+	}
+}
+
+func renameCommand(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		v := loadForUpdate()
+		before := v.Clone()
+
+		var name string
+		if len(args) > 0 {
+			name = args[0]
+		} else if stdinIsTerminal() {
+			name = prompt.StringRequired("New project name (required)")
+		} else {
+			printError("Missing project name, e.g. `gover rename \"My Project\"`\n")
+			exit(exitUsage)
+		}
+
+		name = strings.TrimSpace(name)
+		if name == "" {
+			printError("Project name can't be empty\n")
+			exit(exitUsage)
+		}
+
+		printInfo("Renaming project '%s' -> '%s'\n", v.ProjectName, name)
+		v.ProjectName = name
+		saveChanges(before, v)
+	}
+}
+
+func codenameCommand(fs *flag.FlagSet) func(args []string) {
+	random := fs.Bool("random", false, "pick a codename no version has had yet")
+
+	return func(args []string) {
+		v := loadForUpdate()
+		before := v.Clone()
+
+		var codename string
+		if *random {
+			if len(args) > 0 {
+				printError("--random can't be combined with a codename\n")
+				exit(exitUsage)
+			}
+			codename = setRandomCodename(v).VersionString
+		} else if len(args) > 0 {
+			codename = args[0]
+		} else if stdinIsTerminal() {
+			codename = prompt.StringRequired("New codename (required)")
+		} else {
+			printError("Missing codename, e.g. `gover codename durian`\n")
+			exit(exitUsage)
+		}
+
+		v = setCodename(v, codename)
+		printInfo("Changing codename '%s' -> '%s'\n", before.VersionString, v.VersionString)
+		saveChanges(before, v)
+	}
+}
+
+func setmetaCommand(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		if len(args) < 1 {
+			printError("Missing build metadata, e.g. `gover setmeta gitsha.abcdef`\n")
+			exit(exitUsage)
+		}
+
+		v := loadForUpdate()
+		before := v.Clone()
+		v = setMetadata(v, args[0])
+		saveChanges(before, v)
+	}
+}
+
+func setFieldCommand(fs *flag.FlagSet) func(args []string) {
+	fs.BoolVar(&dryRun, "dry-run", dryRun, "show the change without writing it")
+
+	return func(args []string) {
+		if len(args) < 2 {
+			printError("Missing field or value, e.g. `gover set-field author \"Jane <jane@example.com>\"`\n")
+			exit(exitUsage)
+		}
+		field, value := args[0], strings.TrimSpace(args[1])
+		var target *string
+		v := loadForUpdate()
+		switch field {
+		case "author":
+			target = &v.Author
+		case "description":
+			target = &v.Description
+		default:
+			printError("Unknown field '%s', valid fields are: %s\n", field, strings.Join(settableFields, ", "))
+			exit(exitUsage)
+		}
+		if textVersionFile() {
+			printError("%s only stores the version, use another format to keep the %s\n", filepath.Base(versionFile), field)
+			exit(exitFailure)
+		}
+
+		before := v.Clone()
+		printInfo("Changing %s '%s' -> '%s'\n", field, *target, value)
+		*target = value
+		saveChanges(before, v)
+	}
+}
+
+func fieldCommand(fs *flag.FlagSet) func(args []string) {
+	fs.BoolVar(&dryRun, "dry-run", dryRun, "show the change without writing it")
+
+	return func(args []string) {
+		if len(args) < 1 {
+			printError("Missing action, valid actions are: %s\n", strings.Join(fieldActions, ", "))
+			exit(exitUsage)
+		}
+		action, args := args[0], args[1:]
+		switch {
+		case action != "set" && action != "get" && action != "unset":
+			printError("Unknown action '%s', valid actions are: %s\n", action, strings.Join(fieldActions, ", "))
+			exit(exitUsage)
+		case len(args) < 1:
+			printError("Missing key, e.g. `gover field %s channel`\n", action)
+			exit(exitUsage)
+		case action == "set" && len(args) < 2:
+			printError("Missing value, e.g. `gover field set %s beta`\n", args[0])
+			exit(exitUsage)
+		}
+		key := args[0]
+		checkExtraKey(key)
+
+		if action == "get" {
+			printExtra(loadForRead(), key)
+			return
+		}
+		v := loadForUpdate()
+		if textVersionFile() {
+			printError("%s only stores the version, use another format to keep custom fields\n", filepath.Base(versionFile))
+			exit(exitFailure)
+		}
+		before := v.Clone()
+		if action == "set" {
+			setExtra(v, key, args[1])
+		} else if !unsetExtra(v, key) {
+			return
+		}
+		saveChanges(before, v)
+	}
+}
+
+func serveCommand(fs *flag.FlagSet) func(args []string) {
+	addr := fs.String("addr", ":8080", "address to listen on")
+	once := fs.Bool("once", false, "print the /version response body and exit")
+
+	return func(args []string) {
+		loadForRead()
+		if !*once {
+			serveVersion(*addr)
+			return
+		}
+		body, status := versionResponse()
+		stdout.Write(body)
+		if status != http.StatusOK {
+			exit(exitFailure)
+		}
+	}
+}
+
+func versionCommand(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		printGoverVersion()
+	}
+}
+
+func printGoverVersion() {
+	info := buildinfo.Get()
+	if jsonOutput {
+		printJSON(info)
+		return
+	}
+	fmt.Fprintln(stdout, info)
+}
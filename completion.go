@@ -0,0 +1,342 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// completion describes the commands table, so it's added once the table
+// exists
+func init() {
+	commands = append(commands, command{
+		name:        "completion",
+		usage:       "bash|zsh|fish",
+		description: "Print a shell completion script",
+		examples:    []string{"source <(gover completion bash)", "gover completion fish > ~/.config/fish/completions/gover.fish"},
+		argValues:   completionShells,
+		standalone:  true,
+		setup:       completionCommand,
+	})
+}
+
+func completionCommand(fs *flag.FlagSet) func(args []string) {
+	projects := fs.Bool("projects", false, "print the project directories --project can select, used by the completion scripts")
+
+	return func(args []string) {
+		if *projects {
+			for _, p := range findProjects() {
+				fmt.Fprintln(stdout, p.dir)
+			}
+			return
+		}
+
+		if len(args) < 1 {
+			printError("Missing shell, valid shells are: %s\n", strings.Join(completionShells, ", "))
+			exit(exitUsage)
+		}
+		switch args[0] {
+		case "bash":
+			fmt.Fprint(stdout, bashCompletion(completionCommands()))
+		case "zsh":
+			fmt.Fprint(stdout, zshCompletion(completionCommands()))
+		case "fish":
+			fmt.Fprint(stdout, fishCompletion(completionCommands()))
+		default:
+			printError("Unknown shell '%s', valid shells are: %s\n", args[0], strings.Join(completionShells, ", "))
+			exit(exitUsage)
+		}
+	}
+}
+
+type completionFlag struct {
+	name       string
+	usage      string
+	takesValue bool
+}
+
+// Flag as typed on the command line, single letter flags keep one dash
+func (f completionFlag) arg() string {
+	if len(f.name) == 1 {
+		return "-" + f.name
+	}
+	return "--" + f.name
+}
+
+type completionEntry struct {
+	command
+	flags []completionFlag
+}
+
+func completionFlags(fs *flag.FlagSet) []completionFlag {
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, completionFlag{
+			name:       f.Name,
+			usage:      f.Usage,
+			takesValue: !ok || !boolFlag.IsBoolFlag(),
+		})
+	})
+	return flags
+}
+
+// Every command with the flags it declares itself. Global flags are left to
+// the entry with an empty name, which comes first.
+func completionCommands() []completionEntry {
+	var entries []completionEntry
+	for _, cmd := range commands {
+		if cmd.name == "" {
+			entries = append(entries, completionEntry{command: cmd, flags: completionFlags(flag.CommandLine)})
+			continue
+		}
+
+		fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		cmd.setup(fs)
+		var own []completionFlag
+		for _, f := range completionFlags(fs) {
+			if flag.CommandLine.Lookup(f.name) == nil {
+				own = append(own, f)
+			}
+		}
+		entries = append(entries, completionEntry{command: cmd, flags: own})
+	}
+	return entries
+}
+
+func completionWords(entry completionEntry) (flags string, args string) {
+	var words []string
+	for _, f := range entry.flags {
+		words = append(words, f.arg())
+	}
+	return strings.Join(words, " "), strings.Join(entry.argValues, " ")
+}
+
+// Case pattern matching the global flags that take a value, in both the
+// single and double dash forms
+func valueFlagPattern(flags []completionFlag) string {
+	var patterns []string
+	for _, f := range flags {
+		if !f.takesValue {
+			continue
+		}
+		patterns = append(patterns, "-"+f.name)
+		if len(f.name) > 1 {
+			patterns = append(patterns, "--"+f.name)
+		}
+	}
+	return strings.Join(patterns, "|")
+}
+
+func bashCompletion(entries []completionEntry) string {
+	var names []string
+	for _, entry := range entries[1:] {
+		names = append(names, entry.name)
+	}
+	globalFlags, _ := completionWords(entries[0])
+
+	var b strings.Builder
+	b.WriteString(`# bash completion for gover, generated by ` + "`gover completion bash`" + `
+#
+# Load it in the current shell with:
+#   source <(gover completion bash)
+# or install it for every new shell with:
+#   gover completion bash > /etc/bash_completion.d/gover
+
+_gover() {
+    local cur prev word cmd i
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+
+    case "$prev" in
+        --project|-project)
+            COMPREPLY=($(compgen -W "$(gover completion --projects 2>/dev/null)" -- "$cur"))
+            return
+            ;;
+        --file|-file|-f)
+            COMPREPLY=($(compgen -f -- "$cur"))
+            return
+            ;;
+    esac
+
+    # The command is the first word that isn't a flag or a flag's value
+    cmd=""
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        word="${COMP_WORDS[i]}"
+        case "$word" in
+`)
+	fmt.Fprintf(&b, "            %s)\n                ((i++))\n                ;;\n", valueFlagPattern(entries[0].flags))
+	b.WriteString(`            -*)
+                ;;
+            *)
+                cmd="$word"
+                break
+                ;;
+        esac
+    done
+
+    local flags="` + globalFlags + `" args=""
+    case "$cmd" in
+        "")
+            args="` + strings.Join(names, " ") + `"
+            ;;
+`)
+	for _, entry := range entries[1:] {
+		flags, args := completionWords(entry)
+		fmt.Fprintf(&b, "        %s)\n            flags=\"$flags %s\"\n            args=\"%s\"\n            ;;\n", entry.name, flags, args)
+	}
+	b.WriteString(`    esac
+
+    if [[ "$cur" == -* ]]; then
+        COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+    else
+        COMPREPLY=($(compgen -W "$args" -- "$cur"))
+    fi
+}
+
+complete -o default -F _gover gover
+`)
+	return b.String()
+}
+
+func zshCompletion(entries []completionEntry) string {
+	globalFlags, _ := completionWords(entries[0])
+
+	var b strings.Builder
+	b.WriteString(`#compdef gover
+# zsh completion for gover, generated by ` + "`gover completion zsh`" + `
+#
+# Load it in the current shell with:
+#   source <(gover completion zsh)
+# or install it for every new shell by saving it as _gover in a directory on
+# $fpath:
+#   gover completion zsh > "${fpath[1]}/_gover"
+
+_gover() {
+    local cmd word i
+    local -a flags args commands
+
+    case "${words[CURRENT-1]}" in
+        --project|-project)
+            compadd -- ${(f)"$(gover completion --projects 2>/dev/null)"}
+            return
+            ;;
+        --file|-file|-f)
+            _files
+            return
+            ;;
+    esac
+
+    # The command is the first word that isn't a flag or a flag's value
+    cmd=""
+    for ((i = 2; i < CURRENT; i++)); do
+        word="${words[i]}"
+        case "$word" in
+`)
+	fmt.Fprintf(&b, "            %s)\n                ((i++))\n                ;;\n", valueFlagPattern(entries[0].flags))
+	b.WriteString(`            -*)
+                ;;
+            *)
+                cmd="$word"
+                break
+                ;;
+        esac
+    done
+
+    flags=(` + globalFlags + `)
+    case "$cmd" in
+        "")
+            commands=(
+`)
+	for _, entry := range entries[1:] {
+		fmt.Fprintf(&b, "                %s\n", zshQuote(entry.name+":"+entry.description))
+	}
+	b.WriteString(`            )
+            if [[ "$PREFIX" != -* ]]; then
+                _describe command commands
+                return
+            fi
+            ;;
+`)
+	for _, entry := range entries[1:] {
+		flags, args := completionWords(entry)
+		fmt.Fprintf(&b, "        %s)\n            flags+=(%s)\n            args=(%s)\n            ;;\n", entry.name, flags, args)
+	}
+	b.WriteString(`    esac
+
+    if [[ "$PREFIX" == -* ]]; then
+        compadd -- $flags
+    else
+        compadd -- $args
+    fi
+}
+
+if [[ "$funcstack[1]" == _gover ]]; then
+    _gover "$@"
+else
+    compdef _gover gover
+fi
+`)
+	return b.String()
+}
+
+func fishCompletion(entries []completionEntry) string {
+	var b strings.Builder
+	b.WriteString(`# fish completion for gover, generated by ` + "`gover completion fish`" + `
+#
+# Load it in the current shell with:
+#   gover completion fish | source
+# or install it for every new shell with:
+#   gover completion fish > ~/.config/fish/completions/gover.fish
+
+complete -c gover -f
+`)
+	for _, f := range entries[0].flags {
+		b.WriteString(fishFlag("", f))
+	}
+
+	for _, entry := range entries[1:] {
+		fmt.Fprintf(&b, "\ncomplete -c gover -n __fish_use_subcommand -a %s -d %s\n", entry.name, fishQuote(entry.description))
+		condition := "'__fish_seen_subcommand_from " + entry.name + "'"
+		for _, f := range entry.flags {
+			b.WriteString(fishFlag(condition, f))
+		}
+		if len(entry.argValues) > 0 {
+			fmt.Fprintf(&b, "complete -c gover -n %s -a %s\n", condition, fishQuote(strings.Join(entry.argValues, " ")))
+		}
+	}
+	return b.String()
+}
+
+func fishFlag(condition string, f completionFlag) string {
+	var b strings.Builder
+	b.WriteString("complete -c gover")
+	if condition != "" {
+		b.WriteString(" -n " + condition)
+	}
+	if len(f.name) == 1 {
+		b.WriteString(" -s " + f.name)
+	} else {
+		b.WriteString(" -l " + f.name)
+	}
+	switch {
+	case f.name == "project":
+		b.WriteString(" -x -a '(gover completion --projects 2>/dev/null)'")
+	case f.name == "file" || f.name == "f":
+		b.WriteString(" -r -F")
+	case f.takesValue:
+		b.WriteString(" -r")
+	}
+	b.WriteString(" -d " + fishQuote(f.usage) + "\n")
+	return b.String()
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Project configuration from the config file, and the settings in effect
+// once the version file's own settings are applied on top
+var (
+	config   version.Config
+	settings version.Settings
+)
+
+const starterConfig string = `# gover configuration. Command line flags take precedence over these values,
+# and settings in the version file take precedence over the ones here.
+
+# Default output, either a text/template or JSON
+#format: "{{.ProjectName}}-{{.Version}}"
+#json: false
+
+# Prefix shown in front of versions, in get and templates like commitMessage
+# too once it is set. "" shows bare versions everywhere. The version file
+# always stores the bare version, and either form is accepted on input.
+#versionPrefix: v
+
+# Prefix for tag names, defaults to versionPrefix. "" tags bare versions
+#tagPrefix: v
+
+# Sign tags with GPG, skipped with a warning when git has no signing key
+signTags: false
+
+# Bump even when tracked files have uncommitted changes
+allowDirty: false
+
+# Read "1.2" as 1.2.0 and "v1" as 1.0.0 in init, set and imported versions,
+# the same as passing --loose
+looseVersions: false
+
+# Commit message template used with --commit
+commitMessage: "chore: bump version to {{.Version}}"
+
+# Where the build number comes from, manual or git-count
+buildSource: manual
+
+# What bumps do to the build number, at most one of these can be true
+resetBuildOnBump: false
+bumpBuildOnVersionBump: false
+
+# Take the build number from the CI job on bumps: GITHUB_RUN_NUMBER on GitHub
+# Actions, CI_PIPELINE_IID on GitLab CI or BUILD_NUMBER on Jenkins
+buildFromCI: false
+
+# Add each new version to CHANGELOG.md
+changelog: true
+
+# Update version badges and mentions of the previous version in README.md
+updateReadme: false
+
+# Keep the previous version file as ver.json.bak on each write
+backup: false
+
+# Keep this many timestamped backups like ver.json.bak.20240601T120301
+# instead, removing older ones
+#backupRetention: 5
+
+# Never write backups, the same as passing --no-backup
+#noBackup: true
+
+# Ask before writing a bump, or only before major bumps. Pass --yes to skip
+# the question in scripts.
+confirmBumps: false
+confirmMajor: false
+
+# Seal the version file with a checksum to notice edits made outside of gover
+integrity: false
+
+# Shell commands run before and after bumps
+#hooks:
+#  preBump:
+#    - make docs
+#  postBump:
+#    - make release
+
+# Webhooks posted to after each bump. ${NAME} in the url and headers is read
+# from the environment. The payload is a text/template with .Project, .Old,
+# .New, .Build and .Actor, and json to quote a value.
+#notifications:
+#  - url: "${SLACK_WEBHOOK_URL}"
+#    payload: '{"text": {{json (printf "%s %s -> %s" .Project .Old .New)}}}'
+#  - url: https://deploy.example.com/hooks/version
+#    headers:
+#      Authorization: "Bearer ${DEPLOY_TOKEN}"
+
+# Other files holding a copy of the version
+#syncFiles:
+#  - file: Dockerfile
+#    template: "ARG VERSION={{.Version}}"
+#  - file: docs/index.md
+#    pattern: "Current version: ([0-9.]+)"
+#  - file: internal/build/version.go
+#    goVar: Version
+#    value: "{{.Version}}+{{.Build}}"
+`
+
+// Reads the config file next to the version file, if there is one. Output
+// defaults are applied unless an output flag was already given.
+func loadConfig() {
+	dir := filepath.Dir(versionFile)
+	found := filesIn(dir, version.ConfigFileNames)
+	if len(found) == 0 {
+		return
+	}
+	if len(found) > 1 {
+		var names []string
+		for _, path := range found {
+			names = append(names, filepath.Base(path))
+		}
+		printError("Found multiple config files in %s: %s\n", dir, strings.Join(names, ", "))
+		fmt.Fprintln(os.Stderr, "Remove all but one of them")
+		exit(exitUsage)
+	}
+
+	loaded, unknown, err := version.LoadConfig(found[0])
+	if err != nil {
+		printError("Unable to parse config file %s\n", found[0])
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	logVerbose("Using config file %s", found[0])
+
+	// Output flags aren't settled yet, so warnings can't use printInfo
+	for _, key := range unknown {
+		fmt.Fprintf(os.Stderr, "%s Unknown key '%s' in %s\n", colorize(os.Stderr, colorYellow, "WARNING:"), key, found[0])
+	}
+	config = *loaded
+
+	if !jsonOutput && outputFormat == "" && !quietOutput {
+		jsonOutput = config.JSON
+		outputFormat = config.Format
+	}
+}
+
+// Writes starterConfig next to the version file unless a config file is
+// already there
+func writeStarterConfig() {
+	dir := filepath.Dir(versionFile)
+	if existing := filesIn(dir, version.ConfigFileNames); len(existing) > 0 {
+		printInfo("%s already exists, not writing a starter config\n", existing[0])
+		return
+	}
+
+	path := filepath.Join(dir, version.ConfigFileNames[0])
+	if err := os.WriteFile(path, []byte(starterConfig), 0644); err != nil {
+		printError("Unable to write %s\n", path)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	printInfo("Wrote starter config %s\n", path)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Matches a conventional commit header, e.g. "feat(parser)!: drop v1 syntax"
+var conventionalHeader = regexp.MustCompile(`^([A-Za-z]+)(\([^)]*\))?(!)?: \S`)
+
+// Breaking change footers, the dash form is allowed as a synonym by the spec
+var breakingFooter = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE: `)
+
+type conventionalCommit struct {
+	hash    string
+	subject string
+	level   string
+}
+
+// Bump level implied by a single commit message, empty for commits that
+// don't call for a release (chore, docs, non-conventional messages, ...)
+func commitLevel(subject, body string) string {
+	match := conventionalHeader.FindStringSubmatch(subject)
+	if match == nil {
+		return ""
+	}
+	if match[3] == "!" || breakingFooter.MatchString(body) {
+		return "major"
+	}
+
+	switch strings.ToLower(match[1]) {
+	case "feat":
+		return "minor"
+	case "fix", "perf":
+		return "patch"
+	}
+	return ""
+}
+
+// Commits after the last release, which is the newest tag for a version up to
+// the current one if there is one, or else the last commit that changed the
+// version file. Without either, the whole history is considered. The
+// returned description names the starting point for messages.
+func commitsSinceRelease(v *version.GoVersion) (string, []conventionalCommit, error) {
+	since, description := "", "the first commit"
+	if name := latestTag(v.Version); name != "" {
+		since, description = name, name
+	} else if rel, err := repoRelativeVersionFile(); err == nil {
+		if hash, err := runGit("log", "-1", "--format=%h", "--", rel); err == nil && hash != "" {
+			since, description = hash, fmt.Sprintf("%s (last change to %s)", hash, filepath.Base(versionFile))
+		}
+	}
+
+	// Fields are separated by the unit separator and commits by the record
+	// separator, neither of which shows up in commit messages
+	logArgs := []string{"log", "--format=%h%x1f%s%x1f%b%x1e"}
+	if since != "" {
+		logArgs = append(logArgs, since+"..HEAD")
+	}
+	out, err := runGit(logArgs...)
+	if err != nil {
+		return description, nil, err
+	}
+
+	var commits []conventionalCommit
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.SplitN(strings.TrimSpace(record), "\x1f", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		commits = append(commits, conventionalCommit{
+			hash:    fields[0],
+			subject: fields[1],
+			level:   commitLevel(fields[1], fields[2]),
+		})
+	}
+	return description, commits, nil
+}
+
+// Picks the highest bump level called for by the commits since the last
+// release and prints the commits behind it. Exits without changes when no
+// commit calls for a release.
+func autoLevel(v *version.GoVersion) string {
+	requireGitRepo()
+	since, commits, err := commitsSinceRelease(v)
+	if err != nil {
+		printError("Unable to read commits since %s\n", since)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+
+	level := ""
+	for _, precedence := range version.Levels {
+		for _, c := range commits {
+			if c.level == precedence {
+				level = precedence
+				break
+			}
+		}
+		if level != "" {
+			break
+		}
+	}
+
+	if level == "" {
+		printInfo("No feat, fix, perf or breaking change commits since %s, version unchanged\n", since)
+		exit(exitSuccess)
+	}
+
+	printInfo("Bumping %s version for commits since %s:\n", level, since)
+	for _, c := range commits {
+		if c.level == level {
+			printInfo("  %s %s\n", c.hash, c.subject)
+		}
+	}
+	return level
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Exit codes for diff, matching diff(1)
+const (
+	exitDiffIdentical int = 0
+	exitDiffDifferent int = 1
+)
+
+// Reads the version file as it was committed at ref. The returned bool is
+// false when the file doesn't exist at ref.
+func versionAt(ref string) (*version.GoVersion, bool) {
+	requireGitRepo()
+	if _, err := runGit("rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		printError("'%s' is not a commit in this repository\n", ref)
+		exit(exitUsage)
+	}
+
+	rel, err := repoRelativeVersionFile()
+	if err != nil {
+		printError("Unable to locate the version file within the repository\n")
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	if _, err := runGit("cat-file", "-e", ref+":"+rel); err != nil {
+		return nil, false
+	}
+
+	contents, err := runGit("show", ref+":"+rel)
+	if err != nil {
+		printError("Unable to read %s at %s\n", rel, ref)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	v, err := version.Decode([]byte(contents), version.FormatFor(versionFile))
+	if err != nil {
+		printError("Unable to parse %s at %s\n", rel, ref)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	return v, true
+}
+
+// Prints the version, codename and build of the working tree next to the
+// ones committed at ref and exits 0 if they're the same, 1 if not
+func diffVersion(v *version.GoVersion, ref string) {
+	committed, ok := versionAt(ref)
+	if !ok {
+		fmt.Fprintf(stdout, "%s doesn't exist at %s, this is a new project at %s\n", versionFile, ref, displayVersion(v.Version))
+		exit(exitDiffDifferent)
+	}
+
+	type row struct {
+		field, then, now string
+		newer            int
+	}
+	rows := []row{
+		{"version", displayVersion(committed.Version), displayVersion(v.Version), v.Version.Compare(committed.Version)},
+		{"codename", committed.VersionString, v.VersionString, 0},
+	}
+	// A counted build number isn't stored, so there is nothing to compare
+	if settings.BuildSource != version.BuildSourceGitCount {
+		rows = append(rows, row{"build", strconv.Itoa(committed.Build), strconv.Itoa(v.Build), compareInts(v.Build, committed.Build)})
+	}
+
+	identical := true
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "FIELD\t%s\tWORKING TREE\n", ref)
+	for _, r := range rows {
+		note := ""
+		switch {
+		case r.newer > 0:
+			note = colorize(stdout, colorGreen, "working tree is newer")
+		case r.newer < 0:
+			note = colorize(stdout, colorYellow, ref+" is newer")
+		case r.then != r.now:
+			note = "changed"
+		}
+		if r.then != r.now {
+			identical = false
+		}
+		if note != "" {
+			note = "\t" + note
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s%s\n", r.field, r.then, r.now, note)
+	}
+	w.Flush()
+
+	if !identical {
+		exit(exitDiffDifferent)
+	}
+	exit(exitDiffIdentical)
+}
+
+func compareInts(a int, b int) int {
+	switch {
+	case a > b:
+		return 1
+	case a < b:
+		return -1
+	}
+	return 0
+}
+
+// A field that differs between two version files
+type fileDifference struct {
+	Field string `json:"field"`
+	A     string `json:"a"`
+	B     string `json:"b"`
+	// Newer is "a" or "b" for the version field, when they aren't equal
+	Newer string `json:"newer,omitempty"`
+}
+
+// Loads a version file given on the command line. Like diff(1), a file that
+// can't be read exits 2 so that it isn't taken for a difference.
+func loadComparedFile(path string) *version.GoVersion {
+	v, err := version.Load(path)
+	if err != nil {
+		printError("Unable to read %s\n", path)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitUsage)
+	}
+	return v
+}
+
+// Top level fields of a version file as compact JSON, with the settings
+// flattened in as they are in the file
+func versionFields(v *version.GoVersion) map[string]json.RawMessage {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		printError("Unable to marshal version object\n")
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	var fields map[string]json.RawMessage
+	json.Unmarshal(encoded, &fields)
+	return fields
+}
+
+// A field's value for the table: strings unquoted, lists longer than a few
+// words counted, anything else as JSON
+func describeField(raw json.RawMessage) string {
+	if raw == nil {
+		return "-"
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err == nil && len(raw) > 40 {
+		if len(list) == 1 {
+			return "1 entry"
+		}
+		return fmt.Sprintf("%d entries", len(list))
+	}
+	return string(raw)
+}
+
+// Compares every field of the version files at pathA and pathB, which can
+// be in different formats. The version field says which file is newer. Exits
+// 0 if the files are the same and 1 if they aren't.
+func compareFiles(pathA, pathB string) {
+	a, b := loadComparedFile(pathA), loadComparedFile(pathB)
+	fieldsA, fieldsB := versionFields(a), versionFields(b)
+
+	names := make(map[string]bool)
+	for name := range fieldsA {
+		names[name] = true
+	}
+	for name := range fieldsB {
+		names[name] = true
+	}
+	// version leads, the rest follow in alphabetical order
+	ordered := []string{"version"}
+	delete(names, "version")
+	rest := make([]string, 0, len(names))
+	for name := range names {
+		rest = append(rest, name)
+	}
+	sort.Strings(rest)
+	ordered = append(ordered, rest...)
+
+	var differences []fileDifference
+	for _, name := range ordered {
+		rawA, rawB := fieldsA[name], fieldsB[name]
+		if bytes.Equal(rawA, rawB) {
+			continue
+		}
+		difference := fileDifference{Field: name, A: describeField(rawA), B: describeField(rawB)}
+		if name == "version" && a.Version != nil && b.Version != nil {
+			switch a.Version.Compare(b.Version) {
+			case 1:
+				difference.Newer = "a"
+			case -1:
+				difference.Newer = "b"
+			}
+		}
+		differences = append(differences, difference)
+	}
+
+	if jsonOutput {
+		if differences == nil {
+			differences = []fileDifference{}
+		}
+		printJSON(struct {
+			A           string           `json:"a"`
+			B           string           `json:"b"`
+			Identical   bool             `json:"identical"`
+			Differences []fileDifference `json:"differences"`
+		}{pathA, pathB, len(differences) == 0, differences})
+	} else if len(differences) == 0 {
+		fmt.Fprintf(stdout, "%s and %s are identical\n", pathA, pathB)
+	} else {
+		w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "FIELD\t%s\t%s\n", pathA, pathB)
+		for _, d := range differences {
+			note := ""
+			switch d.Newer {
+			case "a":
+				note = "\t" + colorize(stdout, colorGreen, pathA+" is newer")
+			case "b":
+				note = "\t" + colorize(stdout, colorGreen, pathB+" is newer")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s%s\n", d.Field, d.A, d.B, note)
+		}
+		w.Flush()
+	}
+
+	if len(differences) > 0 {
+		exit(exitDiffDifferent)
+	}
+	exit(exitDiffIdentical)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Subcommands of docker
+var dockerOutputs = []string{"labels", "tags"}
+
+// An image name without a tag, e.g. app or registry.example.com:5000/team/app
+var imageName = regexp.MustCompile(`^(?:[a-zA-Z0-9.-]+(?::[0-9]+)?/)?[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+
+// Characters that can't appear in an image tag
+var invalidTagCharacters = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// Image labels from the OCI annotations spec,
+// https://github.com/opencontainers/image-spec/blob/main/annotations.md
+const (
+	ociVersion  string = "org.opencontainers.image.version"
+	ociRevision string = "org.opencontainers.image.revision"
+	ociCreated  string = "org.opencontainers.image.created"
+)
+
+// --label arguments for docker build. The revision is HEAD's full hash and
+// is left out outside of a git repository.
+func dockerLabels(v *version.GoVersion) []string {
+	labels := []string{
+		"--label", ociVersion + "=" + v.Version.String(),
+	}
+	if revision, err := runGit("rev-parse", "HEAD"); err == nil {
+		labels = append(labels, "--label", ociRevision+"="+revision)
+	} else {
+		logVerbose("Leaving out %s: %s", ociRevision, err)
+	}
+	labels = append(labels, "--label", ociCreated+"="+now().UTC().Format(time.RFC3339))
+	return labels
+}
+
+// Tags for image, from the most to the least specific: 1.4.2, 1.4, 1 and
+// latest. Prereleases only get their own tag, so that 1.5.0-rc.1 doesn't
+// move 1 or latest. Build metadata is kept with the "+" replaced, since it
+// isn't allowed in tags.
+func dockerTags(v *version.GoVersion, image string) []string {
+	full := invalidTagCharacters.ReplaceAllString(v.Version.String(), "-")
+	tags := []string{image + ":" + full}
+	if v.Version.Prerelease() != "" {
+		return tags
+	}
+	return append(tags,
+		fmt.Sprintf("%s:%d.%d", image, v.Version.Major(), v.Version.Minor()),
+		fmt.Sprintf("%s:%d", image, v.Version.Major()),
+		image+":latest",
+	)
+}
+
+// Prints labels or tags on a single line. Every value is made of characters
+// the shell leaves alone, so the line can be used unquoted in $(...).
+func printDocker(v *version.GoVersion, output, image string, tagFlags bool) {
+	switch output {
+	case "labels":
+		fmt.Fprintln(stdout, strings.Join(dockerLabels(v), " "))
+	case "tags":
+		if image == "" {
+			printError("Missing --image, e.g. gover docker tags --image app\n")
+			exit(exitUsage)
+		}
+		if !imageName.MatchString(image) {
+			printError("'%s' is not a valid image name\n", image)
+			exit(exitUsage)
+		}
+		tags := dockerTags(v, image)
+		if tagFlags {
+			for i, tag := range tags {
+				tags[i] = "--tag " + tag
+			}
+		}
+		fmt.Fprintln(stdout, strings.Join(tags, " "))
+	default:
+		printError("Unknown docker output '%s', valid outputs are: %s\n", output, strings.Join(dockerOutputs, ", "))
+		exit(exitUsage)
+	}
+}
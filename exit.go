@@ -0,0 +1,57 @@
+package main
+
+import "os"
+
+// Exit codes shared by every command. Commands whose exit code is their
+// result, like diff and compare, define their own on top of these, with the
+// results that aren't plain success or failure starting at 10.
+const (
+	exitSuccess int = 0
+	// Something went wrong at runtime, e.g. a file couldn't be written
+	exitFailure int = 1
+	// The command line was wrong, nothing was attempted
+	exitUsage int = 2
+	// 3 to 5 are git failures, see git.go
+	exitNotInitialized int = 6
+)
+
+// Documented in the usage message, in order
+var exitCodes = []struct {
+	code        int
+	description string
+}{
+	{exitSuccess, "success"},
+	{exitFailure, "runtime error, or a check that failed"},
+	{exitUsage, "usage error"},
+	{exitGitNotInstalled, "git is not installed"},
+	{exitNotGitRepo, "not inside a git repository"},
+	{exitTagExists, "the tag already exists"},
+	{exitNotInitialized, "no version file, run `gover init`"},
+	{exitCompareOlder, "compare: the current version is older"},
+	{exitCompareNewer, "compare: the current version is newer"},
+	{exitInspectNoInfo, "inspect: the binary has no version info"},
+}
+
+// Carries an exit code from exit up to main
+type exitCode int
+
+// Unwinds to main, which exits with code once deferred calls along the way
+// have run. Helpers deep inside a command exit this way rather than calling
+// os.Exit, so that the lock is always released and a test can recover the
+// code instead of losing the process.
+func exit(code int) {
+	panic(exitCode(code))
+}
+
+// Deferred by main. Releases the lock, then exits with the code passed to
+// exit. Any other panic carries on once the lock is released.
+func handleExit() {
+	r := recover()
+	releaseLock()
+	if code, ok := r.(exitCode); ok {
+		os.Exit(int(code))
+	}
+	if r != nil {
+		panic(r)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Key of an assignment in a .env file, which may be exported
+var envFileKey = regexp.MustCompile(`^(\s*(?:export\s+)?)([A-Za-z_][A-Za-z0-9_]*)\s*=`)
+
+// Values are double quoted when they hold anything a .env parser would treat
+// specially
+func envFileQuote(value string) string {
+	if !strings.ContainsAny(value, " \t#\"'\\$`\n") {
+		return value
+	}
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "$", `\$`, "`", "\\`").Replace(value)
+	return `"` + value + `"`
+}
+
+// KEY=value lines for every field, in the order of getFields
+func envFileLines(v *version.GoVersion, prefix string) ([]string, []string) {
+	var keys, lines []string
+	for _, field := range getFields {
+		value, _ := getField(v, field)
+		key := prefix + strings.ToUpper(field)
+		keys = append(keys, key)
+		lines = append(lines, key+"="+envFileQuote(value))
+	}
+	return keys, lines
+}
+
+// Replaces the lines assigning keys in contents, keeping any export, and
+// appends the keys that aren't there yet. Every other line is kept as it is.
+func mergeEnvFile(contents string, keys []string, lines []string) string {
+	replacement := make(map[string]string, len(keys))
+	for i, key := range keys {
+		replacement[key] = lines[i]
+	}
+
+	existing := strings.Split(strings.TrimSuffix(contents, "\n"), "\n")
+	if contents == "" {
+		existing = nil
+	}
+	written := make(map[string]bool)
+	for i, line := range existing {
+		match := envFileKey.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if line, ok := replacement[match[2]]; ok {
+			existing[i] = match[1] + line
+			written[match[2]] = true
+		}
+	}
+	for i, key := range keys {
+		if !written[key] {
+			existing = append(existing, lines[i])
+		}
+	}
+	return strings.Join(existing, "\n") + "\n"
+}
+
+// Exits unless path is inside the repository, or the version file's
+// directory outside of one, so that a typo doesn't overwrite ~/.env
+func requireInsideProject(path string) {
+	root, ok := projectRoot()
+	if !ok {
+		root = filepath.Dir(versionFile)
+	}
+	abs, err := filepath.Abs(path)
+	if err == nil {
+		root, err = filepath.Abs(root)
+	}
+	if err != nil {
+		printError("Unable to resolve %s\n", path)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	// Compare real paths, in case either one goes through a symlink
+	if resolved, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		abs = filepath.Join(resolved, filepath.Base(abs))
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		printError("%s is outside of %s, use --force to write it anyway\n", path, root)
+		exit(exitUsage)
+	}
+}
+
+// Writes the version to a .env file, replacing the file or with merge just
+// the gover keys in it
+func writeEnvFile(v *version.GoVersion, path string, prefix string, merge bool) {
+	keys, lines := envFileLines(v, prefix)
+	contents := strings.Join(lines, "\n") + "\n"
+
+	mode := os.FileMode(0644)
+	existing, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if merge {
+			contents = mergeEnvFile(string(existing), keys, lines)
+		}
+	case !os.IsNotExist(err):
+		printError("Unable to read %s\n", path)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+
+	if err := os.WriteFile(path, []byte(contents), mode); err != nil {
+		printError("Unable to write %s\n", path)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	printInfo("Wrote %s to %s\n", strings.Join(keys, ", "), path)
+}
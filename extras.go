@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Subcommands of field
+var fieldActions = []string{"set", "get", "unset"}
+
+// Extra field keys can be anything without whitespace
+var extraKey = regexp.MustCompile(`^\S+$`)
+
+func checkExtraKey(key string) {
+	if !extraKey.MatchString(key) {
+		printError("'%s' is not a valid field key, keys can't be empty or contain whitespace\n", key)
+		exit(exitUsage)
+	}
+}
+
+// Prints the value of an extra field. A key that isn't set exits 1, so that
+// scripts can tell it apart from an empty value.
+func printExtra(v *version.GoVersion, key string) {
+	value, ok := v.Extras[key]
+	if !ok {
+		printError("Field '%s' is not set", key)
+		if len(v.Extras) > 0 {
+			keys := make([]string, 0, len(v.Extras))
+			for k := range v.Extras {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			fmt.Fprintf(os.Stderr, ", set fields are: %s", strings.Join(keys, ", "))
+		}
+		fmt.Fprintln(os.Stderr)
+		exit(exitFailure)
+	}
+	fmt.Fprintln(stdout, value)
+}
+
+func setExtra(v *version.GoVersion, key, value string) {
+	previous, ok := v.Extras[key]
+	if v.Extras == nil {
+		v.Extras = make(map[string]string)
+	}
+	v.Extras[key] = value
+	if ok {
+		printInfo("Changing field %s '%s' -> '%s'\n", key, previous, value)
+	} else {
+		printInfo("Setting field %s to '%s'\n", key, value)
+	}
+}
+
+// Removes an extra field, reporting false when it wasn't set. Like unset in
+// a shell that isn't an error.
+func unsetExtra(v *version.GoVersion, key string) bool {
+	if _, ok := v.Extras[key]; !ok {
+		printInfo("Field %s is not set\n", key)
+		return false
+	}
+	delete(v.Extras, key)
+	if len(v.Extras) == 0 {
+		v.Extras = nil
+	}
+	printInfo("Removed field %s\n", key)
+	return true
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Lists the default-named version files present in dir. A VERSION file only
+// counts when there's no other version file, since it's just as likely to be
+// left over from before the project used gover.
+func versionFilesIn(dir string) []string {
+	found := filesIn(dir, version.FileNames)
+	if len(found) == 0 {
+		found = filesIn(dir, []string{version.TextFileName})
+	}
+	return found
+}
+
+// Whether the version file only holds the version
+func textVersionFile() bool {
+	return version.FormatFor(versionFile).Name == version.Text.Name
+}
+
+// A text version file has no project name, so the directory holding it
+// stands in for one
+func nameTextProject(path string, v *version.GoVersion) {
+	if v.ProjectName == "" && version.FormatFor(path).Name == version.Text.Name {
+		v.ProjectName = filepath.Base(filepath.Dir(path))
+	}
+}
+
+// Lists which of the named files are present in dir
+func filesIn(dir string, names []string) []string {
+	var found []string
+	for _, name := range names {
+		candidate := filepath.Join(dir, name)
+		if _, err := os.Stat(candidate); err == nil {
+			found = append(found, candidate)
+		}
+	}
+	return found
+}
+
+// Exits when more than one default-named version file exists in dir, rather
+// than silently picking one of them
+func requireSingleVersionFile(dir string, found []string) {
+	if len(found) < 2 {
+		return
+	}
+
+	var names []string
+	for _, path := range found {
+		names = append(names, filepath.Base(path))
+	}
+	printError("Found multiple version files in %s: %s\n", dir, strings.Join(names, ", "))
+	fmt.Fprintln(os.Stderr, "Remove all but one of them, or choose one with --file")
+	exit(exitUsage)
+}
+
+// Walks up from the working directory looking for a version file, the same
+// way git looks for .git. The search stops at the filesystem root or at the
+// top of the enclosing git repository, whichever comes first.
+func findVersionFile() (string, bool) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+
+	for {
+		found := versionFilesIn(dir)
+		requireSingleVersionFile(dir, found)
+		if len(found) == 1 {
+			return found[0], true
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return "", false
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
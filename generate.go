@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"strconv"
+	"text/template"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+const defaultGenerateOutput string = "version_gen.go"
+
+// The header follows the form go vet and most editors recognize as generated
+// code. Nothing time dependent goes in the file so that regenerating an
+// unchanged version produces no diff.
+var generateTemplate = template.Must(template.New("generate").Parse(`// Code generated by gover generate; DO NOT EDIT.
+
+package {{.Package}}
+
+const (
+	ProjectName = {{.ProjectName}}
+	Version = {{.Version}}
+	VersionString = {{.VersionString}}
+	Build = {{.Build}}
+)
+`))
+
+// Renders the Go source for v, formatted as gofmt would
+func generateSource(v *version.GoVersion, pkg string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := generateTemplate.Execute(&buf, map[string]string{
+		"Package":       pkg,
+		"ProjectName":   strconv.Quote(v.ProjectName),
+		"Version":       strconv.Quote(v.Version.String()),
+		"VersionString": strconv.Quote(v.VersionString),
+		"Build":         strconv.Itoa(v.Build),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// Writes the generated constants to output, leaving the file untouched when
+// it's already up to date
+func generate(v *version.GoVersion, pkg, output string) {
+	if !token.IsIdentifier(pkg) {
+		printError("'%s' is not a valid Go package name\n", pkg)
+		exit(exitUsage)
+	}
+
+	source, err := generateSource(v, pkg)
+	if err != nil {
+		printError("Unable to generate Go source\n")
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+
+	if existing, err := os.ReadFile(output); err == nil && bytes.Equal(existing, source) {
+		logVerbose("%s is up to date", output)
+		return
+	}
+	if dryRun {
+		printInfo("Dry run, %s was not written\n", output)
+		fmt.Fprint(stdout, string(source))
+		return
+	}
+
+	if err := os.WriteFile(output, source, 0644); err != nil {
+		printError("Unable to write %s\n", output)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	logVerbose("Wrote %s", output)
+}
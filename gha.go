@@ -0,0 +1,73 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Writes step outputs for GitHub Actions, set by --gha
+var ghaOutput bool
+
+// Exits unless GitHub Actions gave the step an output file
+func requireGitHubOutput() {
+	if os.Getenv("GITHUB_OUTPUT") == "" {
+		printError("--gha writes to the file named by GITHUB_OUTPUT, which isn't set, is this running in GitHub Actions?\n")
+		exit(exitFailure)
+	}
+}
+
+// Appends the fields of v to $GITHUB_OUTPUT, with previous_version as well
+// after a change, and adds a notice summarizing the version to the run
+func writeGitHubOutputs(before, v *version.GoVersion) {
+	var outputs strings.Builder
+	for _, field := range getFields {
+		value, _ := getField(v, field)
+		writeGitHubOutput(&outputs, field, value)
+	}
+	if before != nil && before.Version != nil {
+		writeGitHubOutput(&outputs, "previous_version", settings.ValuePrefix()+before.Version.String())
+	}
+
+	path := os.Getenv("GITHUB_OUTPUT")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err == nil {
+		_, err = f.WriteString(outputs.String())
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		printError("Unable to write the step outputs to %s\n", path)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+
+	notice := formatVersionInfo(v)
+	if before != nil && before.Version != nil && before.Version.String() != v.Version.String() {
+		notice = fmt.Sprintf("%s -> %s", displayVersion(before.Version), notice)
+	}
+	fmt.Fprintf(infoOutput(), "::notice title=gover::%s\n", escapeWorkflowData(notice))
+}
+
+// name=value, or the heredoc form with a random delimiter for values that
+// span lines
+func writeGitHubOutput(w *strings.Builder, name, value string) {
+	if !strings.ContainsAny(value, "\r\n") {
+		fmt.Fprintf(w, "%s=%s\n", name, value)
+		return
+	}
+	random := make([]byte, 8)
+	rand.Read(random)
+	delimiter := "ghadelimiter_" + hex.EncodeToString(random)
+	fmt.Fprintf(w, "%s<<%s\n%s\n%s\n", name, delimiter, value, delimiter)
+}
+
+// Escapes a workflow command's message the way the runner unescapes it
+func escapeWorkflowData(data string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(data)
+}
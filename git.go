@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+const defaultCommitMessage string = "chore: bump version to {{.Version}}"
+
+// Exit codes for git failures, distinct so that scripts can tell them apart
+const (
+	exitGitNotInstalled int = 3
+	exitNotGitRepo      int = 4
+	exitTagExists       int = 5
+)
+
+// Runs git from the directory containing the version file and returns its
+// trimmed stdout. Git's stderr is included in the returned error.
+func runGit(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = filepath.Dir(versionFile)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return "", fmt.Errorf("git %s: %s", args[0], err)
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Exits unless git is installed and the version file is inside a work tree
+func requireGitRepo() {
+	if _, err := exec.LookPath("git"); err != nil {
+		printError("git is not installed or not on PATH\n")
+		exit(exitGitNotInstalled)
+	}
+
+	if _, err := runGit("rev-parse", "--is-inside-work-tree"); err != nil {
+		printError("%s is not inside a git repository\n", filepath.Dir(versionFile))
+		exit(exitNotGitRepo)
+	}
+}
+
+// Number of commits reachable from HEAD. A shallow clone only has part of the
+// history, so its count would be wrong and is refused.
+func gitCommitCount() (int, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return 0, fmt.Errorf("git is not installed or not on PATH")
+	}
+
+	shallow, err := runGit("rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return 0, err
+	}
+	if shallow == "true" {
+		return 0, fmt.Errorf("the repository is a shallow clone, run `git fetch --unshallow` for an accurate count")
+	}
+
+	count, err := runGit("rev-list", "--count", "HEAD")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(count)
+}
+
+// Short hash of HEAD, marked -dirty when tracked files have uncommitted
+// changes
+func gitCommitHash() (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", fmt.Errorf("git is not installed or not on PATH")
+	}
+
+	hash, err := runGit("rev-parse", "--short", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	status, err := runGit("status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return "", err
+	}
+	if status != "" {
+		hash += "-dirty"
+	}
+	return hash, nil
+}
+
+func gitTagExists(name string) bool {
+	_, err := runGit("rev-parse", "--quiet", "--verify", "refs/tags/"+name)
+	return err == nil
+}
+
+func tagName(v *version.GoVersion) string {
+	return settings.EffectiveTagPrefix() + v.Version.String()
+}
+
+// Version named by a tag, which has to be the tag prefix followed by a semver
+// version. Other tags, including ones with a different prefix, don't count.
+func tagVersion(name string) (*semver.Version, bool) {
+	prefix := settings.EffectiveTagPrefix()
+	rest := strings.TrimPrefix(name, prefix)
+	if (rest == name && prefix != "") || rest == "" || rest[0] < '0' || rest[0] > '9' {
+		return nil, false
+	}
+	v, err := semver.NewVersion(rest)
+	if err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// Newest tag by semver precedence that names a version no higher than
+// ceiling, or the newest of all with a nil ceiling. Empty when there is none.
+func latestTag(ceiling *semver.Version) string {
+	out, err := runGit("tag", "--list", settings.EffectiveTagPrefix()+"*")
+	if err != nil {
+		return ""
+	}
+	var latest string
+	var latestVersion *semver.Version
+	for _, name := range strings.Split(out, "\n") {
+		v, ok := tagVersion(name)
+		if !ok || (ceiling != nil && v.GreaterThan(ceiling)) {
+			continue
+		}
+		if latestVersion == nil || v.GreaterThan(latestVersion) {
+			latest, latestVersion = name, v
+		}
+	}
+	return latest
+}
+
+// Highest version among the tags, used by init --from-git. Tags that aren't
+// the tag prefix followed by a semver version are skipped, and with none
+// left init starts from defaultVersion.
+func versionFromTags() *semver.Version {
+	requireGitRepo()
+	name := latestTag(nil)
+	if name == "" {
+		printInfo("No %s<version> tags found, starting from %s\n", settings.EffectiveTagPrefix(), defaultVersion)
+		return defaultVersion
+	}
+	v, _ := tagVersion(name)
+	printInfo("Starting from the latest tag, %s\n", name)
+	return v
+}
+
+// How a tag is signed. keyID is passed to git as --local-user, otherwise
+// git picks the key.
+type tagSigning struct {
+	sign     bool
+	explicit bool
+	keyID    string
+}
+
+// Creates an annotated tag for the current version at HEAD, moving an
+// existing tag only when force is set
+func createTag(v *version.GoVersion, force bool, signing tagSigning) string {
+	requireGitRepo()
+
+	name := tagName(v)
+	if gitTagExists(name) && !force {
+		printError("Tag %s already exists, use --force to move it\n", name)
+		exit(exitTagExists)
+	}
+
+	// signTags from the config file shouldn't break tagging on machines
+	// without a key
+	if signing.sign && !signing.explicit && signing.keyID == "" {
+		if key, err := runGit("config", "--get", "user.signingkey"); err != nil || key == "" {
+			printWarning("No signing key is configured in git, creating %s unsigned\n", name)
+			signing.sign = false
+		}
+	}
+
+	args := []string{"tag", "--annotate", "--message", fmt.Sprintf("%s %s", v.ProjectName, name)}
+	switch {
+	case signing.keyID != "":
+		args = append(args, "--local-user", signing.keyID)
+	case signing.sign:
+		args = append(args, "--sign")
+	}
+	if force {
+		args = append(args, "--force")
+	}
+	args = append(args, name, "HEAD")
+
+	if _, err := runGit(args...); err != nil {
+		printError("Unable to create tag %s\n", name)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	return name
+}
+
+// Checks the GPG signature on the tag for the current version, git's output
+// is shown as it is so that gpg problems can be diagnosed
+func verifyTag(v *version.GoVersion) string {
+	requireGitRepo()
+
+	name := tagName(v)
+	if !gitTagExists(name) {
+		printError("Tag %s doesn't exist\n", name)
+		exit(exitFailure)
+	}
+	if _, err := runGit("tag", "--verify", name); err != nil {
+		printError("Unable to verify the signature on %s\n", name)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	return name
+}
+
+// Path of the version file relative to the top of the repository, in the
+// same form git prints paths
+func repoRelativeVersionFile() (string, error) {
+	return repoRelativePath(versionFile)
+}
+
+// Path relative to the top of the repository, in the same form git prints
+// paths. Relative paths are taken from the version file's directory.
+func repoRelativePath(path string) (string, error) {
+	top, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	top, err = filepath.EvalSymlinks(top)
+	if err != nil {
+		return "", err
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(versionFile), path)
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(top, filepath.Join(dir, filepath.Base(path)))
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// Exits if tracked files have uncommitted changes, listing them. Files gover
+// writes itself are allowed, since an earlier bump that wasn't committed yet
+// is a common and legitimate state, and so are staged changes when they're
+// meant to go into the bump commit. Outside a git repository there is nothing
+// to check.
+func requireCleanTree(allowStaged bool) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return
+	}
+	if _, err := runGit("rev-parse", "--is-inside-work-tree"); err != nil {
+		return
+	}
+
+	allowed := make(map[string]bool)
+	owned := []string{versionFile, changelogFileName}
+	for _, target := range settings.SyncFiles {
+		owned = append(owned, target.File)
+	}
+	for _, path := range owned {
+		if rel, err := repoRelativePath(path); err == nil {
+			allowed[rel] = true
+		}
+	}
+
+	diffs := [][]string{{"diff", "--name-only"}}
+	if !allowStaged {
+		diffs = append(diffs, []string{"diff", "--cached", "--name-only"})
+	}
+	seen := make(map[string]bool)
+	var dirty []string
+	for _, args := range diffs {
+		out, err := runGit(args...)
+		if err != nil {
+			printError("Unable to check for uncommitted changes\n")
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitFailure)
+		}
+		for _, path := range strings.Split(out, "\n") {
+			if path != "" && !allowed[path] && !seen[path] {
+				seen[path] = true
+				dirty = append(dirty, path)
+			}
+		}
+	}
+	if len(dirty) > 0 {
+		printError("The working tree has uncommitted changes, commit or stash them or use --force to bump anyway\n")
+		for _, path := range dirty {
+			fmt.Fprintf(os.Stderr, "  %s\n", path)
+		}
+		exit(exitFailure)
+	}
+}
+
+// Exits if anything other than the version file is staged, so that a bump
+// commit doesn't sweep up unrelated changes
+func requireNothingStaged() {
+	rel, err := repoRelativeVersionFile()
+	if err != nil {
+		printError("Unable to locate the version file within the repository\n")
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+
+	staged, err := runGit("diff", "--cached", "--name-only")
+	if err != nil {
+		printError("Unable to list staged files\n")
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+
+	var others []string
+	for _, path := range strings.Split(staged, "\n") {
+		if path != "" && path != rel {
+			others = append(others, path)
+		}
+	}
+	if len(others) > 0 {
+		printError("Other files are already staged, use --allow-staged to commit them with the version bump\n")
+		for _, path := range others {
+			fmt.Fprintf(os.Stderr, "  %s\n", path)
+		}
+		exit(exitFailure)
+	}
+}
+
+func renderCommitMessage(v *version.GoVersion, messageTemplate string) string {
+	return renderTemplate(parseTemplate("commit message", messageTemplate), v)
+}
+
+// Stages the version file and any other given files, then commits them along
+// with anything else staged
+func commitVersionFile(message string, files ...string) {
+	add := append([]string{"add", "--", filepath.Base(versionFile)}, files...)
+	if _, err := runGit(add...); err != nil {
+		printError("Unable to stage the version file\n")
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+
+	if _, err := runGit("commit", "--message", message); err != nil {
+		printError("Unable to commit the version file\n")
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+}
+
+// Exits unless the current branch tracks an upstream branch, checked before
+// anything is changed so that a bump isn't left half finished
+func requireUpstream() {
+	if _, err := runGit("rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}"); err != nil {
+		printError("The current branch has no upstream to push to, set one with `git push --set-upstream` or use --remote\n")
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+}
+
+// Remote that tags are pushed to: the one given, else the current branch's
+// upstream remote, else origin
+func pushRemote(remote string) string {
+	if remote != "" {
+		return remote
+	}
+	if branch, err := runGit("symbolic-ref", "--short", "HEAD"); err == nil {
+		if upstream, err := runGit("config", "--get", "branch."+branch+".remote"); err == nil && upstream != "" {
+			return upstream
+		}
+	}
+	return "origin"
+}
+
+// Pushes the current branch, to its upstream unless remote is given
+func pushCommit(remote string) {
+	args := []string{"push"}
+	if remote != "" {
+		args = append(args, remote, "HEAD")
+	}
+	if _, err := runGit(args...); err != nil {
+		printError("Unable to push the bump commit\n")
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+}
+
+// Pushes a single tag, replacing it on the remote when force is set
+func pushTag(name string, remote string, force bool) {
+	remote = pushRemote(remote)
+	args := []string{"push", remote, "refs/tags/" + name}
+	if force {
+		args = append(args, "--force")
+	}
+	if _, err := runGit(args...); err != nil {
+		printError("Unable to push tag %s to %s\n", name, remote)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	printInfo("Pushed %s to %s\n", name, remote)
+}
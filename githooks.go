@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// git hooks install-hooks can write
+var gitHookNames = []string{"pre-commit", "pre-push"}
+
+// Lines around the part of a hook that belongs to gover, so that it can be
+// updated or removed without touching the rest of the script
+const (
+	gitHookBegin string = "# >>> gover >>>"
+	gitHookEnd   string = "# <<< gover <<<"
+)
+
+// The commands gover adds to a hook. pre-commit counts the build up and
+// stages the file so that it goes into the commit being made.
+func gitHookCommands(hook, rel string) string {
+	file := shellQuote(rel)
+	switch hook {
+	case "pre-commit":
+		return fmt.Sprintf("gover --file %s build --quiet && git add %s || exit 1", file, file)
+	case "pre-push":
+		return fmt.Sprintf("gover --file %s verify || exit 1", file)
+	}
+	return ""
+}
+
+// Parses the comma separated --hooks list
+func selectGitHooks(list string) []string {
+	var hooks []string
+	for _, hook := range strings.Split(list, ",") {
+		hook = strings.TrimSpace(hook)
+		if gitHookCommands(hook, "") == "" {
+			printError("Unknown hook '%s', valid hooks are: %s\n", hook, strings.Join(gitHookNames, ", "))
+			exit(exitUsage)
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks
+}
+
+// Directory git runs hooks from, core.hooksPath when it's set
+func gitHooksDir() string {
+	dir, err := runGit("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		printError("Unable to find the git hooks directory\n")
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(filepath.Dir(versionFile), dir)
+	}
+	return dir
+}
+
+// Splits a hook into what comes before and after the gover section, which
+// is reported as missing when the markers aren't there
+func splitGitHook(script string) (string, string, bool) {
+	start := strings.Index(script, gitHookBegin+"\n")
+	if start < 0 {
+		return script, "", false
+	}
+	end := strings.Index(script[start:], gitHookEnd+"\n")
+	if end < 0 {
+		return script, "", false
+	}
+	return script[:start], script[start+end+len(gitHookEnd)+1:], true
+}
+
+// Adds the gover section to each hook, or removes it with uninstall. A hook
+// that already exists keeps its contents, gover's commands run after them.
+func installGitHooks(hooks []string, uninstall bool) {
+	requireGitRepo()
+	rel, err := repoRelativeVersionFile()
+	if err != nil {
+		printError("Unable to locate the version file within the repository\n")
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	dir := gitHooksDir()
+
+	for _, hook := range hooks {
+		path := filepath.Join(dir, hook)
+		existing, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			printError("Unable to read %s\n", path)
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitFailure)
+		}
+		script := string(existing)
+		before, after, installed := splitGitHook(script)
+
+		var updated string
+		switch {
+		case uninstall && !installed:
+			printInfo("%s has no gover section\n", path)
+			continue
+		case uninstall:
+			updated = before + after
+			if strings.TrimSpace(strings.TrimPrefix(updated, "#!/bin/sh\n")) == "" {
+				updated = ""
+			}
+		default:
+			if script != "" && !installed && !shellScript(script) {
+				printError("%s isn't a shell script, add `%s` to it by hand\n", path, gitHookCommands(hook, rel))
+				exit(exitFailure)
+			}
+			if before == "" {
+				before = "#!/bin/sh\n"
+			} else if !strings.HasSuffix(before, "\n") {
+				before += "\n"
+			}
+			section := fmt.Sprintf("%s\n# Added by gover install-hooks, remove with `gover install-hooks --uninstall`\n%s\n%s\n", gitHookBegin, gitHookCommands(hook, rel), gitHookEnd)
+			updated = before + section + after
+			if !installed && endsWithExit(before) {
+				printWarning("%s exits before reaching the gover section, move it above the exit\n", path)
+			}
+		}
+		if updated == script {
+			printInfo("%s is up to date\n", path)
+			continue
+		}
+
+		if dryRun {
+			switch {
+			case updated == "":
+				printInfo("Would remove %s\n", path)
+			case uninstall:
+				printInfo("Would remove the gover section from %s\n", path)
+			default:
+				printInfo("Would add `%s` to %s\n", gitHookCommands(hook, rel), path)
+			}
+			continue
+		}
+		if updated == "" {
+			err = os.Remove(path)
+		} else {
+			err = writeGitHook(path, updated)
+		}
+		if err != nil {
+			printError("Unable to write %s\n", path)
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitFailure)
+		}
+
+		switch {
+		case updated == "":
+			printInfo("Removed %s, it only ran gover\n", path)
+		case uninstall:
+			printInfo("Removed the gover section from %s\n", path)
+		default:
+			printInfo("Installed the %s hook in %s\n", hook, path)
+		}
+	}
+}
+
+// Writes the hook and makes it executable, git skips hooks that aren't
+func writeGitHook(path, script string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0755)
+}
+
+// Whether commands can be appended to script, which is run by sh when it
+// has no #! line
+func shellScript(script string) bool {
+	first, _, _ := strings.Cut(script, "\n")
+	if !strings.HasPrefix(first, "#!") {
+		return true
+	}
+	fields := strings.Fields(strings.TrimPrefix(first, "#!"))
+	if len(fields) == 0 {
+		return true
+	}
+	shell := filepath.Base(fields[0])
+	if shell == "env" && len(fields) > 1 {
+		shell = fields[1]
+	}
+	switch shell {
+	case "sh", "bash", "dash", "zsh", "ksh":
+		return true
+	}
+	return false
+}
+
+// Whether the last command of script is exit, which would skip anything
+// appended after it
+func endsWithExit(script string) bool {
+	lines := strings.Split(strings.TrimSpace(script), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	return last == "exit" || strings.HasPrefix(last, "exit ")
+}
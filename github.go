@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+const defaultGitHubAPI string = "https://api.github.com"
+
+const githubTimeout time.Duration = 30 * time.Second
+
+// Owner and repository in the URLs git accepts for a GitHub remote:
+// git@github.com:owner/repo.git, ssh://git@github.com/owner/repo.git and
+// https://github.com/owner/repo
+var githubRemote = regexp.MustCompile(`^(?:[a-z+]+://)?(?:[^@/]+@)?[^:/]+(?::[0-9]+)?[:/]([^/]+)/([^/]+?)(?:\.git)?/?$`)
+
+// The fields of a GitHub release gover sets
+type githubRelease struct {
+	ID         int64  `json:"id,omitempty"`
+	TagName    string `json:"tag_name"`
+	Name       string `json:"name"`
+	Body       string `json:"body"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+	HTMLURL    string `json:"html_url,omitempty"`
+}
+
+// Talks to the GitHub REST API for one repository
+type githubClient struct {
+	api   string
+	token string
+	owner string
+	repo  string
+	http  *http.Client
+}
+
+// Error returned for responses outside of 2xx, with GitHub's message
+type githubError struct {
+	status  int
+	message string
+}
+
+func (e *githubError) Error() string {
+	return fmt.Sprintf("GitHub API returned %d: %s", e.status, e.message)
+}
+
+// Splits a remote URL into the owner and repository
+func parseGitHubRemote(remoteURL string) (string, string, bool) {
+	match := githubRemote.FindStringSubmatch(strings.TrimSpace(remoteURL))
+	if match == nil {
+		return "", "", false
+	}
+	return match[1], match[2], true
+}
+
+// Client for the repository remote points at, authenticated with
+// GITHUB_TOKEN. GITHUB_API_URL points it at GitHub Enterprise, Actions sets
+// it for each job.
+func newGitHubClient(remote string) *githubClient {
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		printError("GITHUB_TOKEN is not set, it needs to be a token that can write releases\n")
+		exit(exitFailure)
+	}
+
+	remoteURL, err := runGit("remote", "get-url", remote)
+	if err != nil {
+		printError("Unable to read the URL of remote %s\n", remote)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	owner, repo, ok := parseGitHubRemote(remoteURL)
+	if !ok {
+		printError("Unable to find the GitHub owner and repository in %s's URL %s\n", remote, remoteURL)
+		exit(exitFailure)
+	}
+
+	api := os.Getenv("GITHUB_API_URL")
+	if api == "" {
+		api = defaultGitHubAPI
+	}
+	logVerbose("GitHub repository %s/%s from %s, API at %s", owner, repo, remote, api)
+	return &githubClient{
+		api:   strings.TrimRight(api, "/"),
+		token: token,
+		owner: owner,
+		repo:  repo,
+		http:  &http.Client{Timeout: githubTimeout},
+	}
+}
+
+// Sends a request to path under the repository, decoding the response into
+// out when it isn't nil
+func (c *githubClient) do(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		encoded, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	endpoint := fmt.Sprintf("%s/repos/%s/%s%s", c.api, url.PathEscape(c.owner), url.PathEscape(c.repo), path)
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("User-Agent", "gover")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var failure struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&failure)
+		if failure.Message == "" {
+			failure.Message = http.StatusText(resp.StatusCode)
+		}
+		return &githubError{status: resp.StatusCode, message: failure.Message}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// The release for tag, reporting false when there isn't one
+func (c *githubClient) releaseByTag(tag string) (*githubRelease, bool, error) {
+	var release githubRelease
+	err := c.do(http.MethodGet, "/releases/tags/"+url.PathEscape(tag), nil, &release)
+	var apiErr *githubError
+	if errors.As(err, &apiErr) && apiErr.status == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &release, true, nil
+}
+
+// Body of the release, the notes file when given and otherwise the
+// version's changelog section
+func releaseNotes(v *version.GoVersion, notesFile string) string {
+	if notesFile != "" {
+		notes, err := os.ReadFile(notesFile)
+		if err != nil {
+			printError("Unable to read %s\n", notesFile)
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitFailure)
+		}
+		return string(notes)
+	}
+	notes, ok := changelogSection(v)
+	if !ok {
+		printWarning("%s has no section for %s, the release will have no notes\n", changelogFileName, v.Version)
+	}
+	return notes
+}
+
+// Creates the GitHub release for the current version's tag, or updates it
+// when it already exists so that the command can be run again. The tag has
+// to be on the remote already, GitHub would create it from the default
+// branch otherwise.
+func publishGitHubRelease(v *version.GoVersion, titleTemplate, notesFile, remote string, draft bool) {
+	requireGitRepo()
+	remote = pushRemote(remote)
+	tag := tagName(v)
+
+	title := tag
+	if titleTemplate != "" {
+		title = renderTemplate(parseTemplate("title", titleTemplate), v)
+	}
+	release := githubRelease{
+		TagName:    tag,
+		Name:       title,
+		Body:       releaseNotes(v, notesFile),
+		Draft:      draft,
+		Prerelease: v.Version.Prerelease() != "",
+	}
+
+	if dryRun {
+		printInfo("Dry run, no GitHub release was created for %s\n", tag)
+		fmt.Fprintf(stdout, "Title: %s\nPrerelease: %t\n\n%s\n", release.Name, release.Prerelease, release.Body)
+		return
+	}
+
+	client := newGitHubClient(remote)
+	if out, err := runGit("ls-remote", "--tags", remote, "refs/tags/"+tag); err != nil || out == "" {
+		printError("%s doesn't have tag %s, push it first with `gover tag --push`\n", remote, tag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		exit(exitFailure)
+	}
+
+	existing, found, err := client.releaseByTag(tag)
+	if err != nil {
+		printError("Unable to look up the GitHub release for %s\n", tag)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+
+	var published githubRelease
+	if found {
+		err = client.do(http.MethodPatch, fmt.Sprintf("/releases/%d", existing.ID), release, &published)
+	} else {
+		err = client.do(http.MethodPost, "/releases", release, &published)
+	}
+	if err != nil {
+		printError("Unable to publish the GitHub release for %s\n", tag)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+
+	action := "Created"
+	if found {
+		action = "Updated"
+	}
+	printInfo("%s GitHub release %s: %s\n", action, tag, published.HTMLURL)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Matches the module directive of a go.mod file, the path may be quoted
+var moduleDirective = regexp.MustCompile(`(?m)^module[ \t]+("?)([^"\s]+)("?)`)
+
+// Major version suffix of a module path, /v2 and up
+var majorSuffix = regexp.MustCompile(`/v([2-9]|[1-9][0-9]+)$`)
+
+// Nearest go.mod from the version file's directory up to the top of the
+// repository, empty if there is none
+func findGoMod() string {
+	root, inRepo := projectRoot()
+	dir := filepath.Dir(versionFile)
+	for {
+		path := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if !inRepo || dir == root || parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Module path for a major version, following the Go modules rule that v2 and
+// up end in /vN
+func modulePathFor(path string, major int64) string {
+	path = majorSuffix.ReplaceAllString(path, "")
+	if major >= 2 {
+		path += fmt.Sprintf("/v%d", major)
+	}
+	return path
+}
+
+// Points the module directive of the nearest go.mod at the new major version
+// after a major bump to v2 or above, optionally rewriting the module's own
+// imports to match. Returns the files changed, nothing when the bump doesn't
+// cross a major version or there is no go.mod.
+func updateGoMod(previous *version.GoVersion, v *version.GoVersion, rewriteImports bool) []string {
+	major := v.Version.Major()
+	if major < 2 || major == previous.Version.Major() {
+		return nil
+	}
+	path := findGoMod()
+	if path == "" {
+		return nil
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		printError("Unable to read %s\n", path)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	match := moduleDirective.FindSubmatchIndex(contents)
+	if match == nil {
+		printWarning("%s has no module directive, not updated\n", path)
+		return nil
+	}
+	oldPath := string(contents[match[4]:match[5]])
+	if strings.HasPrefix(oldPath, "gopkg.in/") {
+		printWarning("%s uses gopkg.in versioning, update its module path by hand\n", path)
+		return nil
+	}
+	newPath := modulePathFor(oldPath, major)
+	if newPath == oldPath {
+		return nil
+	}
+
+	updated := string(contents[:match[4]]) + newPath + string(contents[match[5]:])
+	if err := os.WriteFile(path, []byte(updated), 0644); err != nil {
+		printError("Unable to write %s\n", path)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	printInfo("Updated the module path in %s to %s\n", path, newPath)
+
+	changed := []string{path}
+	if !rewriteImports {
+		printInfo("Imports of %s inside the module need to change to %s, or bump with --rewrite-imports\n", oldPath, newPath)
+		return changed
+	}
+	rewritten := rewriteModuleImports(filepath.Dir(path), oldPath, newPath)
+	printInfo("Rewrote imports of %s in %d files\n", oldPath, len(rewritten))
+	return append(changed, rewritten...)
+}
+
+// Replaces imports of oldPath and its packages with newPath in every Go file
+// of the module in dir. Nested modules and vendor directories are left alone.
+func rewriteModuleImports(dir string, oldPath string, newPath string) []string {
+	var changed []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == dir {
+				return nil
+			}
+			if d.Name() == "vendor" || strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+
+		ok, err := rewriteFileImports(path, oldPath, newPath)
+		if ok {
+			changed = append(changed, path)
+		}
+		return err
+	})
+	if err != nil {
+		printError("Unable to rewrite imports in %s\n", dir)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	return changed
+}
+
+// Rewrites the import paths in one file, reporting whether it changed. Only
+// the import declarations are touched.
+func rewriteFileImports(path string, oldPath string, newPath string) (bool, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), path, contents, parser.ImportsOnly)
+	if err != nil {
+		return false, err
+	}
+
+	type replacement struct {
+		start, end int
+		text       string
+	}
+	var replacements []replacement
+	for _, spec := range file.Imports {
+		imported, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || (imported != oldPath && !strings.HasPrefix(imported, oldPath+"/")) {
+			continue
+		}
+		// A file parsed from contents alone starts at offset 1
+		start := int(spec.Path.Pos()) - 1
+		replacements = append(replacements, replacement{
+			start: start,
+			end:   start + len(spec.Path.Value),
+			text:  strconv.Quote(newPath + strings.TrimPrefix(imported, oldPath)),
+		})
+	}
+	if len(replacements) == 0 {
+		return false, nil
+	}
+
+	sort.Slice(replacements, func(i, j int) bool {
+		return replacements[i].start > replacements[j].start
+	})
+	for _, r := range replacements {
+		contents = append(contents[:r.start], append([]byte(r.text), contents[r.end:]...)...)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(path, contents, info.Mode().Perm())
+}
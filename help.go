@@ -0,0 +1,176 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// help lists the commands table, so it's added once the table exists
+func init() {
+	help := command{
+		name:        "help",
+		usage:       "[<command>]",
+		description: "Show help for gover or one of its commands",
+		examples:    []string{"gover help patch"},
+		standalone:  true,
+		setup:       helpCommand,
+	}
+	for _, cmd := range commands {
+		if cmd.name != "" {
+			help.argValues = append(help.argValues, cmd.name)
+		}
+	}
+	help.argValues = append(help.argValues, help.name)
+	commands = append(commands, help)
+}
+
+func helpCommand(fs *flag.FlagSet) func(args []string) {
+	return func(args []string) {
+		if len(args) == 0 {
+			printUsage(stdout)
+			return
+		}
+
+		cmd, ok := findCommand(args[0])
+		if !ok || cmd.name == "" {
+			unknownCommand(args[0])
+		}
+		cmdFlags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		cmd.setup(cmdFlags)
+		commandUsage(stdout, cmd, cmdFlags, addGlobalFlags(cmdFlags))
+	}
+}
+
+// Largest edit distance at which an unknown command is taken for a typo
+const maxSuggestionDistance int = 2
+
+// Exits after reporting an unknown command, suggesting the closest one if
+// the name looks like a typo of it
+func unknownCommand(name string) {
+	printError("Unknown command '%s'\n", name)
+	if suggestion := suggestCommand(name); suggestion != "" {
+		fmt.Fprintf(os.Stderr, "Did you mean '%s'?\n", suggestion)
+	}
+	printUsage(os.Stderr)
+	exit(exitUsage)
+}
+
+func suggestCommand(name string) string {
+	best, bestDistance := "", maxSuggestionDistance+1
+	for _, cmd := range commands {
+		if cmd.name == "" {
+			continue
+		}
+		// Short names are within a couple of edits of almost anything, so
+		// they have to keep at least one character
+		distance := editDistance(name, cmd.name)
+		if distance < bestDistance && distance < len(name) {
+			best, bestDistance = cmd.name, distance
+		}
+	}
+	return best
+}
+
+// Levenshtein distance between a and b
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// Prints the overview of every command and the global flags
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gover [flags] <command> [arguments]")
+	fmt.Fprintln(w, "\nWith no command, gover prints the current version.")
+
+	fmt.Fprintln(w, "\nCommands:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands {
+		if cmd.name != "" {
+			fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.description)
+		}
+	}
+	tw.Flush()
+	fmt.Fprintln(w, "\nAny other command runs gover-<command> from PATH, with the version in GOVER_FILE,\nGOVER_VERSION, GOVER_NAME and GOVER_BUILD.")
+
+	fmt.Fprintln(w, "\nGlobal flags:")
+	printDefaults(w, flag.CommandLine)
+
+	fmt.Fprintln(w, "\nExit codes:")
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, c := range exitCodes {
+		fmt.Fprintf(tw, "  %d\t%s\n", c.code, c.description)
+	}
+	tw.Flush()
+	fmt.Fprintln(w, "\nRun `gover help <command>` for details on a command.")
+}
+
+// Prints a command's usage, its own flags first and then the global ones
+func commandUsage(w io.Writer, cmd command, fs *flag.FlagSet, global *flag.FlagSet) {
+	own := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.VisitAll(func(f *flag.Flag) {
+		if global.Lookup(f.Name) == nil {
+			own.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+
+	name := "gover"
+	if cmd.name != "" {
+		name += " " + cmd.name
+	}
+	fmt.Fprintf(w, "Usage: %s %s\n", name, cmd.usage)
+	fmt.Fprintf(w, "\n%s\n", cmd.description)
+	if cmd.details != "" {
+		fmt.Fprintf(w, "\n%s\n", cmd.details)
+	}
+	if hasFlags(own) {
+		fmt.Fprintln(w, "\nFlags:")
+		printDefaults(w, own)
+	}
+	if len(cmd.examples) > 0 {
+		fmt.Fprintln(w, "\nExamples:")
+		for _, example := range cmd.examples {
+			fmt.Fprintf(w, "  %s\n", example)
+		}
+	}
+	fmt.Fprintln(w, "\nGlobal flags:")
+	printDefaults(w, global)
+}
+
+func printDefaults(w io.Writer, fs *flag.FlagSet) {
+	output := fs.Output()
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+	fs.SetOutput(output)
+}
+
+func hasFlags(fs *flag.FlagSet) bool {
+	found := false
+	fs.VisitAll(func(*flag.Flag) {
+		found = true
+	})
+	return found
+}
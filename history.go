@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/subtlepseudonym/go-prompt"
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+func confirmUndo(message string, yes bool) {
+	printInfo("%s\n", message)
+	if yes {
+		return
+	}
+	if !stdinIsTerminal() {
+		printError("stdin is not a terminal, use --yes to confirm\n")
+		exit(exitUsage)
+	}
+	if !prompt.ConfirmWithDefault("Proceed? (y/N)", false) {
+		fmt.Fprintln(os.Stderr, "Aborted")
+		exit(exitSuccess)
+	}
+}
+
+// Reverts the most recent version change. The last history entry is
+// preferred, falling back to the newest backup kept when backups are on.
+// The reverted entry is kept so that a second undo redoes it rather than
+// walking further back through history.
+func undo(v *version.GoVersion, yes bool) *version.GoVersion {
+	if v.Undone != nil {
+		entry := *v.Undone
+		confirmUndo(fmt.Sprintf("The last change was already undone, redoing %s -> %s", displayVersion(entry.Previous), displayVersion(entry.Version)), yes)
+
+		v.Version = entry.Version
+		v.Build = entry.Build
+		if entry.VersionString != "" || entry.PreviousBuild != nil {
+			v.VersionString = entry.VersionString
+		}
+		v.History = append(v.History, entry)
+		v.Undone = nil
+		return v
+	}
+
+	if len(v.History) > 0 {
+		entry := v.History[len(v.History)-1]
+		if entry.Previous == nil {
+			printError("%s is the first recorded version, there is no version before it to undo to\n", displayVersion(entry.Version))
+			exit(exitFailure)
+		}
+		confirmUndo(fmt.Sprintf("Undoing %s -> %s from %s", displayVersion(entry.Previous), displayVersion(entry.Version), entry.Timestamp.Format(time.RFC3339)), yes)
+
+		v.Version = entry.Previous
+		if entry.PreviousBuild != nil {
+			v.Build = *entry.PreviousBuild
+			v.VersionString = entry.PreviousVersionString
+		} else {
+			printWarning("The build and codename before %s weren't recorded, keeping build %d and '%s'\n", displayVersion(entry.Version), v.Build, v.VersionString)
+		}
+		v.History = v.History[:len(v.History)-1]
+		v.Undone = &entry
+		return v
+	}
+
+	backupFile, err := version.LatestBackup(versionFile)
+	if err == nil {
+		contents, err := os.ReadFile(backupFile)
+		if err != nil {
+			printError("Unable to read backup file %s\n", backupFile)
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitFailure)
+		}
+		backup, err := version.Decode(contents, version.FormatFor(versionFile))
+		if err != nil {
+			printError("Unable to parse backup file %s\n", backupFile)
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitFailure)
+		}
+		confirmUndo(fmt.Sprintf("Restoring %s - %s %s build %d from %s", backup.ProjectName, backup.VersionString, displayVersion(backup.Version), backup.Build, backupFile), yes)
+		return backup
+	}
+
+	fmt.Fprintln(os.Stderr, "Nothing left to undo")
+	exit(exitFailure)
+	return v
+}
+
+// A state rollback can return to, from a history entry or a backup
+type rollbackTarget struct {
+	version  *semver.Version
+	codename string
+	build    int
+	// The version before the first history entry comes without its build
+	hasBuild bool
+	source   string
+}
+
+// States the version file has been in, newest first: each history entry,
+// the version before the first one, then any backups
+func rollbackTargets(v *version.GoVersion) []rollbackTarget {
+	var targets []rollbackTarget
+	for i := len(v.History) - 1; i >= 0; i-- {
+		entry := v.History[i]
+		targets = append(targets, rollbackTarget{
+			version:  entry.Version,
+			codename: entry.VersionString,
+			build:    entry.Build,
+			hasBuild: true,
+			source:   "history entry from " + entry.Timestamp.Format(time.RFC3339),
+		})
+	}
+	if len(v.History) > 0 && v.History[0].Previous != nil {
+		first := v.History[0]
+		target := rollbackTarget{version: first.Previous, source: "version before the first history entry"}
+		if first.PreviousBuild != nil {
+			target.codename = first.PreviousVersionString
+			target.build = *first.PreviousBuild
+			target.hasBuild = true
+		}
+		targets = append(targets, target)
+	}
+
+	backups, _ := version.Backups(versionFile)
+	if _, err := os.Stat(versionFile + ".bak"); err == nil {
+		backups = append(backups, versionFile+".bak")
+	}
+	for _, path := range backups {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		backup, err := version.Decode(contents, version.FormatFor(versionFile))
+		if err != nil || backup.Version == nil {
+			logVerbose("Skipping backup %s: %v", path, err)
+			continue
+		}
+		targets = append(targets, rollbackTarget{
+			version:  backup.Version,
+			codename: backup.VersionString,
+			build:    backup.Build,
+			hasBuild: true,
+			source:   "backup " + path,
+		})
+	}
+	return targets
+}
+
+// Returns v with the version, codename and build of the newest recorded
+// state at target. The history is left alone, the rollback is recorded on
+// top of it when saved.
+func rollback(v *version.GoVersion, target *semver.Version, yes bool) *version.GoVersion {
+	targets := rollbackTargets(v)
+	var found *rollbackTarget
+	for i := range targets {
+		if targets[i].version.String() == target.String() {
+			found = &targets[i]
+			break
+		}
+	}
+	if found == nil {
+		printError("No recorded state has version %s\n", target)
+		listed := make(map[string]bool)
+		var available []string
+		for _, t := range targets {
+			if !listed[t.version.String()] {
+				listed[t.version.String()] = true
+				available = append(available, t.version.String())
+			}
+		}
+		if len(available) == 0 {
+			fmt.Fprintln(os.Stderr, "There is no history or backup to roll back to")
+		} else {
+			fmt.Fprintf(os.Stderr, "Available versions, newest first: %s\n", strings.Join(available, ", "))
+		}
+		exit(exitUsage)
+	}
+
+	confirmUndo(fmt.Sprintf("Rolling back %s -> %s, using the %s", displayVersion(v.Version), displayVersion(found.version), found.source), yes)
+	v.Version = found.version
+	if found.codename != "" {
+		v.VersionString = found.codename
+	} else {
+		printWarning("The codename of %s wasn't recorded, keeping '%s'\n", displayVersion(found.version), v.VersionString)
+	}
+	if found.hasBuild {
+		v.Build = found.build
+	} else {
+		printWarning("The build of %s wasn't recorded, keeping %d\n", displayVersion(found.version), v.Build)
+	}
+	return v
+}
+
+// Prints the history newest first
+func printHistory(v *version.GoVersion) {
+	newestFirst := make([]version.HistoryEntry, 0, len(v.History))
+	for i := len(v.History) - 1; i >= 0; i-- {
+		newestFirst = append(newestFirst, v.History[i])
+	}
+
+	if jsonOutput {
+		printJSON(newestFirst)
+		return
+	}
+
+	if len(newestFirst) == 0 {
+		fmt.Fprintln(stdout, "No version history recorded")
+		return
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIMESTAMP\tPREVIOUS\tVERSION\tBUILD")
+	for _, entry := range newestFirst {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", entry.Timestamp.Format(time.RFC3339), displayVersion(entry.Previous), displayVersion(entry.Version), entry.Build)
+	}
+	w.Flush()
+}
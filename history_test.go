@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver"
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+func TestUndoRestoresBuildAndCodename(t *testing.T) {
+	v := &version.GoVersion{
+		ProjectName:   "demo",
+		Version:       semver.MustParse("1.2.3"),
+		VersionString: "apple",
+		Build:         5,
+	}
+	before := v.Clone()
+	if err := v.BumpMinor(); err != nil {
+		t.Fatal(err)
+	}
+	v.VersionString = "kiwano"
+	v.IncrementBuild()
+	v.RecordHistory(before)
+
+	tests := []struct {
+		name     string
+		version  string
+		codename string
+		build    int
+	}{
+		{name: "undo", version: "1.2.3", codename: "apple", build: 5},
+		{name: "redo", version: "1.3.0", codename: "kiwano", build: 6},
+		{name: "undo again", version: "1.2.3", codename: "apple", build: 5},
+	}
+	for _, test := range tests {
+		v = undo(v, true)
+		if got := v.Version.String(); got != test.version || v.VersionString != test.codename || v.Build != test.build {
+			t.Errorf("%s left %s '%s' build %d, want %s '%s' build %d", test.name, got, v.VersionString, v.Build, test.version, test.codename, test.build)
+		}
+	}
+}
+
+func TestUndoWithoutPreviousBuild(t *testing.T) {
+	// Entries written before schema 7 only have the previous version
+	v := &version.GoVersion{
+		Version:       semver.MustParse("1.3.0"),
+		VersionString: "kiwano",
+		Build:         6,
+		History: []version.HistoryEntry{
+			{Previous: semver.MustParse("1.2.3"), Version: semver.MustParse("1.3.0"), Build: 6, VersionString: "kiwano"},
+		},
+	}
+	v = undo(v, true)
+	if got := v.Version.String(); got != "1.2.3" || v.VersionString != "kiwano" || v.Build != 6 {
+		t.Errorf("undo left %s '%s' build %d, want 1.2.3 'kiwano' build 6", got, v.VersionString, v.Build)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/Masterminds/semver"
+)
+
+// Runs each command through the shell from the directory holding the
+// version file, with the old and new versions in its environment. Output is
+// streamed through, onto stderr when stdout is reserved for machine readable
+// output. Stops at the first command that fails.
+func runHooks(stage string, commands []string, previous, current *semver.Version) error {
+	var out io.Writer = os.Stdout
+	if jsonOutput || outputFormat != "" {
+		out = os.Stderr
+	}
+
+	for _, command := range commands {
+		logVerbose("Running %s hook: %s", stage, command)
+
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", command)
+		} else {
+			cmd = exec.Command("sh", "-c", command)
+		}
+		cmd.Dir = filepath.Dir(versionFile)
+		cmd.Env = append(os.Environ(),
+			"GOVER_OLD_VERSION="+previous.String(),
+			"GOVER_NEW_VERSION="+current.String(),
+		)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = out
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook '%s': %w", stage, command, err)
+		}
+	}
+	return nil
+}
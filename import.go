@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Masterminds/semver"
+)
+
+// Files other tools keep a project's version in, looked for by init in this
+// order
+var importFileNames = []string{"VERSION", "package.json", "pyproject.toml"}
+
+var errNoImportedVersion = errors.New("no version found")
+
+// Reads the version out of a plain VERSION file, the "version" key of a
+// package.json or the version entry of a pyproject.toml
+func readImportedVersion(path string) (string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	switch filepath.Ext(path) {
+	case ".json":
+		var manifest struct {
+			Version string `json:"version"`
+		}
+		if err := json.Unmarshal(contents, &manifest); err != nil {
+			return "", err
+		}
+		if manifest.Version == "" {
+			return "", errNoImportedVersion
+		}
+		return manifest.Version, nil
+	case ".toml":
+		var manifest struct {
+			Version string `toml:"version"`
+			Project struct {
+				Version string `toml:"version"`
+			} `toml:"project"`
+			Tool struct {
+				Poetry struct {
+					Version string `toml:"version"`
+				} `toml:"poetry"`
+			} `toml:"tool"`
+		}
+		if _, err := toml.Decode(string(contents), &manifest); err != nil {
+			return "", err
+		}
+		for _, found := range []string{manifest.Project.Version, manifest.Tool.Poetry.Version, manifest.Version} {
+			if found != "" {
+				return found, nil
+			}
+		}
+		return "", errNoImportedVersion
+	}
+
+	// Anything else holds a bare version on its first line
+	for _, line := range strings.Split(string(contents), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line, nil
+		}
+	}
+	return "", errNoImportedVersion
+}
+
+// Starting version offered by init, taken from path or else from the first
+// of importFileNames next to the version file. Falls back to defaultVersion
+// when there's nothing to import or the value isn't semver.
+func importedVersion(path string) *semver.Version {
+	if path == "" {
+		for _, name := range importFileNames {
+			candidate := filepath.Join(filepath.Dir(versionFile), name)
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+				break
+			}
+		}
+		if path == "" {
+			return defaultVersion
+		}
+	} else if _, err := os.Stat(path); err != nil {
+		printError("Unable to import a version from %s\n", path)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitUsage)
+	}
+
+	found, err := readImportedVersion(path)
+	if err != nil {
+		printWarning("Unable to read a version from %s: %s\n", path, err)
+		return defaultVersion
+	}
+	v, err := parseVersionInput(found)
+	if err != nil {
+		printWarning("%s has version '%s', which isn't valid semver\n", path, found)
+		return defaultVersion
+	}
+	printInfo("Found version %s in %s\n", v, path)
+	return v
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"debug/buildinfo"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Masterminds/semver"
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Exit codes for inspect
+const (
+	exitInspectMatch    int = 0
+	exitInspectMismatch int = 1
+	exitInspectNoInfo   int = 12
+)
+
+// Version information a Go binary carries
+type embeddedVersion struct {
+	goVersion string
+	module    string
+	revision  string
+	modified  bool
+	// Variables set with -X whose names follow defaultLdflagsVars, by field
+	stamped map[string]string
+}
+
+// Reads the build information the go command embeds in binaries, along with
+// the -X flags it was built with
+func readEmbeddedVersion(path string) (embeddedVersion, error) {
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return embeddedVersion{}, err
+	}
+
+	embedded := embeddedVersion{
+		goVersion: info.GoVersion,
+		module:    info.Main.Version,
+		stamped:   make(map[string]string),
+	}
+	if embedded.module == "(devel)" {
+		embedded.module = ""
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			embedded.revision = setting.Value
+		case "vcs.modified":
+			embedded.modified = setting.Value == "true"
+		case "-ldflags":
+			embedded.stamped = stampedFields(setting.Value)
+		}
+	}
+	return embedded, nil
+}
+
+// Picks the gover fields out of the -X flags in an -ldflags value. Any
+// package counts, only the variable name has to match.
+func stampedFields(flags string) map[string]string {
+	fields := make(map[string]string)
+	args := splitLdflags(flags)
+	for i := 0; i < len(args); i++ {
+		var definition string
+		switch {
+		case args[i] == "-X" && i+1 < len(args):
+			i++
+			definition = args[i]
+		case strings.HasPrefix(args[i], "-X="):
+			definition = strings.TrimPrefix(args[i], "-X=")
+		default:
+			continue
+		}
+
+		name, value, ok := strings.Cut(definition, "=")
+		if !ok {
+			continue
+		}
+		name = name[strings.LastIndex(name, ".")+1:]
+		for field, variable := range defaultLdflagsVars {
+			if name == variable {
+				fields[field] = value
+			}
+		}
+	}
+	return fields
+}
+
+// Splits -ldflags the way the go command does, on spaces outside of single
+// or double quotes
+func splitLdflags(flags string) []string {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range flags {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args
+}
+
+// Prints what the binary at path says about its version next to the version
+// file, exiting 1 when they disagree
+func inspectBinary(v *version.GoVersion, path string) {
+	embedded, err := readEmbeddedVersion(path)
+	if err != nil {
+		printError("No embedded version info found in %s\n", path)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitInspectNoInfo)
+	}
+
+	binaryVersion := embedded.stamped["version"]
+	if binaryVersion == "" {
+		binaryVersion = embedded.module
+	}
+	if binaryVersion == "" {
+		printError("No embedded version info found in %s\n", path)
+		fmt.Fprintf(os.Stderr, "It was built with %s without a module version or -X %s\n", embedded.goVersion, defaultLdflagsVars["version"])
+		exit(exitInspectNoInfo)
+	}
+
+	type row struct {
+		field, binary, file string
+		compared            bool
+	}
+	rows := []row{{"version", binaryVersion, v.Version.String(), true}}
+	for _, field := range []string{"codename", "build", "commit"} {
+		if stamped, ok := embedded.stamped[field]; ok {
+			value, _ := getField(v, field)
+			rows = append(rows, row{field, stamped, value, field != "commit"})
+		}
+	}
+	if embedded.revision != "" {
+		revision := embedded.revision
+		if embedded.modified {
+			revision += " (modified)"
+		}
+		rows = append(rows, row{"revision", revision, "", false})
+	}
+	rows = append(rows, row{"go", embedded.goVersion, "", false})
+
+	mismatch := false
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "FIELD\tBINARY\t%s\n", filepath.Base(versionFile))
+	for _, r := range rows {
+		note := ""
+		if r.compared && !sameField(r.field, r.binary, r.file) {
+			note = "\t" + colorize(stdout, colorRed, "mismatch")
+			mismatch = true
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s%s\n", r.field, r.binary, r.file, note)
+	}
+	w.Flush()
+
+	if mismatch {
+		exit(exitInspectMismatch)
+	}
+	exit(exitInspectMatch)
+}
+
+// Versions are compared as semver, so that v1.2.0 matches 1.2.0
+func sameField(field, binary, file string) bool {
+	if field != "version" {
+		return binary == file
+	}
+	a, errA := semver.NewVersion(binary)
+	b, errB := semver.NewVersion(file)
+	if errA != nil || errB != nil {
+		return binary == file
+	}
+	return a.Equal(b) && a.Metadata() == b.Metadata()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+const defaultLdflagsFields string = "version,codename,build"
+
+// Variable names used when --vars doesn't map a field, matching the
+// constants written by gover generate
+var defaultLdflagsVars = map[string]string{
+	"version":     "Version",
+	"name":        "ProjectName",
+	"codename":    "VersionString",
+	"build":       "Build",
+	"commit":      "Commit",
+	"author":      "Author",
+	"description": "Description",
+}
+
+// Parses a comma separated list of field=Variable pairs
+func parseLdflagsVars(mapping string) map[string]string {
+	vars := make(map[string]string, len(defaultLdflagsVars))
+	for field, name := range defaultLdflagsVars {
+		vars[field] = name
+	}
+	if mapping == "" {
+		return vars
+	}
+
+	for _, pair := range strings.Split(mapping, ",") {
+		field, name, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if _, known := defaultLdflagsVars[field]; !ok || !known || name == "" {
+			printError("Invalid variable mapping '%s', expected field=Variable with fields from: %s\n", pair, strings.Join(getFields, ", "))
+			exit(exitUsage)
+		}
+		vars[field] = name
+	}
+	return vars
+}
+
+// Quotes a single -X argument the way the go command splits -ldflags, which
+// accepts single or double quotes but no escapes inside them
+func quoteLdflag(arg string) string {
+	if !strings.Contains(arg, "'") {
+		return "'" + arg + "'"
+	}
+	if !strings.Contains(arg, `"`) {
+		return `"` + arg + `"`
+	}
+	printError("%s contains both single and double quotes, which -ldflags can't represent\n", arg)
+	exit(exitFailure)
+	return ""
+}
+
+// Builds the -X flags setting pkg's variables to the listed fields. The
+// result is meant to be substituted inside double quotes, e.g.
+// go build -ldflags "$(gover ldflags --pkg example.com/app/buildinfo)"
+func ldflags(v *version.GoVersion, pkg, fields, mapping string) string {
+	if pkg == "" {
+		printError("Missing package path, e.g. `gover ldflags --pkg github.com/me/app/internal/buildinfo`\n")
+		exit(exitUsage)
+	}
+	vars := parseLdflagsVars(mapping)
+
+	var flags []string
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		value, ok := getField(v, field)
+		if !ok {
+			printError("Unknown field '%s', valid fields are: %s\n", field, strings.Join(getFields, ", "))
+			exit(exitUsage)
+		}
+		flags = append(flags, "-X "+quoteLdflag(fmt.Sprintf("%s.%s=%s", pkg, vars[field], value)))
+	}
+	return strings.Join(flags, " ")
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+)
+
+// JSON output of list, one per version file. A file that can't be loaded
+// only has its path and error, Build is a pointer so that it's left out there
+// while a real build 0 is still listed.
+type listEntry struct {
+	Path          string `json:"path"`
+	ProjectName   string `json:"name,omitempty"`
+	Version       string `json:"version,omitempty"`
+	VersionString string `json:"versionString,omitempty"`
+	Build         *int   `json:"build,omitempty"`
+	Error         string `json:"error,omitempty"`
+	// prefix is the project's versionPrefix for the text table
+	prefix string
+}
+
+// Prints every version file under the working directory. A file that can't
+// be read is listed with its error rather than stopping the listing.
+func listProjects(all bool) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		printError("Unable to read the working directory\n")
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+
+	var entries []listEntry
+	for _, p := range walkProjects(cwd, all) {
+		entry := listEntry{Path: p.dir}
+		if p.file != "" {
+			entry.Path = filepath.ToSlash(filepath.Join(p.dir, filepath.Base(p.file)))
+		}
+		if p.err != nil {
+			entry.Error = p.err.Error()
+		} else {
+			entry.ProjectName = p.v.ProjectName
+			entry.VersionString = p.v.VersionString
+			build := p.v.Build
+			entry.Build = &build
+			if p.v.Version != nil {
+				entry.Version = p.v.Version.String()
+				entry.prefix = config.Settings.Override(p.v.Settings).DisplayPrefix()
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	if jsonOutput {
+		if entries == nil {
+			entries = []listEntry{}
+		}
+		printJSON(entries)
+		return
+	}
+
+	if len(entries) == 0 {
+		printInfo("No version files found under %s\n", cwd)
+		return
+	}
+
+	// Errors follow the table, they'd break up its columns otherwise
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tNAME\tVERSION\tCODENAME\tBUILD")
+	var failed []listEntry
+	for _, entry := range entries {
+		if entry.Error != "" {
+			failed = append(failed, entry)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\tv%s\t%s\t%d\n", entry.Path, entry.ProjectName, entry.Version, entry.VersionString, *entry.Build)
+	}
+	w.Flush()
+	for _, entry := range failed {
+		fmt.Fprintf(stdout, "%s: ERROR: %s\n", entry.Path, entry.Error)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const defaultLockTimeout time.Duration = 10 * time.Second
+
+// How long to wait for another gover process to finish with the version
+// file, set by --lock-timeout
+var lockTimeout time.Duration = defaultLockTimeout
+
+// Returned by tryLockFile when another process holds the lock
+var errLocked = errors.New("locked")
+
+// The lock held on the version file, if any
+var heldLock *os.File
+
+func lockFilePath() string {
+	return versionFile + ".lock"
+}
+
+// Takes an advisory lock on ver.json.lock for the load-modify-write cycle,
+// waiting up to lockTimeout for another gover process to release it. The
+// lock file is removed on release, so after locking it's checked to still be
+// the file at the lock path, otherwise a process that opened it before the
+// removal could share the lock with one that created a new file.
+func acquireLock() {
+	path := lockFilePath()
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			printError("Unable to create lock file %s\n", path)
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitFailure)
+		}
+
+		err = tryLockFile(f)
+		if err == nil {
+			held, statErr := f.Stat()
+			current, err := os.Stat(path)
+			if statErr == nil && err == nil && os.SameFile(held, current) {
+				heldLock = f
+				logVerbose("Locked %s", path)
+				return
+			}
+			unlockFile(f)
+		}
+		f.Close()
+		if err != nil && !errors.Is(err, errLocked) {
+			printError("Unable to lock %s\n", path)
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitFailure)
+		}
+
+		if time.Now().After(deadline) {
+			printError("Another gover process holds the lock on %s, gave up after %s\n", versionFile, lockTimeout)
+			exit(exitFailure)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// Releases the lock taken by acquireLock, if there is one
+func releaseLock() {
+	if heldLock == nil {
+		return
+	}
+	releaseLockFile(heldLock, lockFilePath())
+	heldLock = nil
+}
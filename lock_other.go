@@ -0,0 +1,17 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package main
+
+import "os"
+
+// Platforms without flock or LockFileEx don't lock the version file
+func tryLockFile(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) {}
+
+func releaseLockFile(f *os.File, path string) {
+	f.Close()
+	os.Remove(path)
+}
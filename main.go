@@ -1,14 +1,25 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/Masterminds/semver"
 	"github.com/subtlepseudonym/go-prompt"
+	"github.com/subtlepseudonym/gover/pkg/version"
+	"golang.org/x/term"
 )
 
 // *semver.Version objects can't be const, which is lame (but understandable)
@@ -19,174 +30,1205 @@ const defaultProjectName string = "GoVer Project"
 const defaultVersionString string = "canteloupe"
 const defaultBuild int = 0
 
-type GoVersion struct {
-	ProjectName   string          `json:"name"`
-	Version       *semver.Version `json:"version"`
-	VersionString string          `json:"versionString"`
-	Build         int             `json:"build"`
+// Path to the version file in use, set by the --file flag or GOVER_FILE
+var versionFile string = versionFileName
+
+// Whether versionFile was chosen by the user rather than found by searching
+var explicitFile bool
+
+// Project chosen with --project, and the directory it was resolved to
+// relative to the top of the repository
+var projectSelector string
+var projectLabel string
+
+// Enables extra diagnostic output on stderr, set by the --verbose flag
+var verbose bool
+
+// Prints gover's own version instead of running a command, set by --version
+var showVersion bool
+
+// Prints only the version, set by --quiet
+var quietOutput bool
+
+// Prints the version from before a bump ahead of the new one in quiet mode,
+// set by --show-previous
+var showPrevious bool
+
+// Requested output is written here. In quiet mode os.Stdout is pointed at
+// stderr so that nothing else ends up in a command substitution.
+var stdout io.Writer = os.Stdout
+
+// Skips writing the version file, set by --dry-run
+var dryRun bool
+
+// Skips backing up the version file whatever the settings say, set by
+// --no-backup
+var noBackup bool
+
+// Re-seals a version file edited outside of gover, set by --accept-changes
+var acceptChanges bool
+
+// Loads a version file that breaks gover's invariants, for fixing it by
+// hand or with gover, set by --no-validate
+var noValidate bool
+
+// Refuses a version file with fields gover doesn't know instead of warning
+// that they'll be dropped, set by --strict-fields
+var strictFields bool
+
+// Fills in missing version numbers in versions given to init, set and
+// imports, set by --loose
+var looseVersions bool
+
+// Template used in place of the default version output, set by --format
+var outputFormat string
+var outputTemplate *template.Template
+
+// Prints version information as JSON and moves other output to stderr, set
+// by the --json flag
+var jsonOutput bool
+
+// Exit codes for compare. 1 and 2 keep their usual meaning of runtime and
+// usage errors so that a failure can't be mistaken for a comparison result.
+const (
+	exitCompareEqual int = 0
+	exitCompareOlder int = 10
+	exitCompareNewer int = 11
+)
+
+// Environment variable prefixes must keep the names valid shell identifiers
+var envPrefixPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Values for init supplied on the command line, empty strings are prompted for
+type initOptions struct {
+	name     string
+	version  string
+	codename string
+	build    string
+	yes      bool
+	config   bool
+	fromGit  bool
+	// importFrom is a file to take the starting version from, by default
+	// init looks for one of importFileNames
+	importFrom string
+	calver     string
+	timezone   string
+	// randomCodename picks the codename from codenameWords
+	randomCodename bool
+	// author and description are only asked for on a terminal, so that
+	// piped answers keep their order
+	author      string
+	description string
 }
 
-func initialize() *GoVersion {
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+func initialize(opts initOptions) *version.GoVersion {
 	// Check to make sure that project is not already versioned by gover
-	_, err := os.OpenFile(versionFileName, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
-	if os.IsExist(err) {
-		fmt.Println("This project is already versioned with gover")
-		fmt.Printf("Do you have a %s file in your root directory for another reason?\n", versionFileName)
-		os.Exit(2)
+	if _, err := os.Stat(versionFile); err == nil {
+		printError("%s already exists, this project is already versioned with gover\n", versionFile)
+		exit(exitUsage)
 	}
 
-	newVersion := GoVersion{}
-	newVersion.ProjectName = prompt.StringRequired("Project name (required)")
+	// Prompting without a terminal would hang, so the answers are read from
+	// stdin one per line instead
+	if !stdinIsTerminal() {
+		pipedAnswers = bufio.NewScanner(os.Stdin)
+	}
 
-	startingVersion := prompt.String("Current version (default=0.1.0)")
-	if startingVersion == "" {
-		newVersion.Version = semver.MustParse("v0.1.0")
-	} else {
+	// A text version file has nowhere to keep the other fields
+	text := textVersionFile()
+
+	if opts.randomCodename && opts.codename != "" {
+		printError("--random-codename can't be combined with --codename\n")
+		exit(exitUsage)
+	}
+
+	// Calendar versioning is checked before anything is asked
+	var calver *version.CalVer
+	if opts.timezone != "" && opts.calver == "" {
+		printError("--timezone is only used with --calver\n")
+		exit(exitUsage)
+	}
+	if opts.calver != "" {
+		calver = &version.CalVer{Pattern: opts.calver, Timezone: opts.timezone}
+		if err := calver.Validate(); err != nil {
+			printError("Unable to use calendar versioning\n")
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitUsage)
+		}
+		if text {
+			printError("%s only stores the version, calendar versioning needs one of the other formats\n", filepath.Base(versionFile))
+			exit(exitUsage)
+		}
+	}
+
+	newVersion := version.GoVersion{SchemaVersion: version.CurrentSchema, CalVer: calver}
+	newVersion.Touch(now())
+	newVersion.ProjectName = opts.name
+	if text {
+		newVersion.ProjectName = filepath.Base(filepath.Dir(versionFile))
+	} else if newVersion.ProjectName == "" {
+		newVersion.ProjectName = promptProjectName()
+	}
+
+	if opts.version != "" {
 		var err error // need to declare because we can't redeclare newVersion.Version
-		newVersion.Version, err = semver.NewVersion(startingVersion)
+		newVersion.Version, err = parseVersionInput(opts.version)
 		if err != nil {
-			fmt.Println("There was an error parsing the version you provided")
-			os.Exit(1)
+			printError("Unable to parse version '%s'\n", opts.version)
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitFailure)
+		}
+	} else if newVersion.CalVer != nil {
+		// The first calendar version is today's, there's nothing to ask
+		newVersion.Version, _ = newVersion.CalVer.Next(nil, now())
+	} else if opts.fromGit {
+		// Without a terminal there's nothing to pre-fill, so the tag is
+		// taken as if it were given with --version
+		newVersion.Version = versionFromTags()
+		if pipedAnswers == nil {
+			newVersion.Version = promptStartingVersion(newVersion.Version)
 		}
+	} else {
+		newVersion.Version = promptStartingVersion(importedVersion(opts.importFrom))
 	}
-	newVersion.VersionString = prompt.StringRequired("Version name (required)")
 
-	buildNumStr := prompt.String("Current build number (default=0)")
-	if buildNumStr == "" {
-		newVersion.Build = 0
-	} else {
+	newVersion.VersionString = opts.codename
+	if opts.randomCodename && !text {
+		setRandomCodename(&newVersion)
+	} else if newVersion.VersionString == "" && !text {
+		newVersion.VersionString = promptInitCodename()
+	}
+
+	newVersion.Author = opts.author
+	newVersion.Description = opts.description
+	if pipedAnswers == nil && !text {
+		if newVersion.Author == "" {
+			newVersion.Author = promptOptional("Author")
+		}
+		if newVersion.Description == "" {
+			newVersion.Description = promptOptional("Description")
+		}
+	}
+
+	switch {
+	case text:
+	case opts.build != "":
 		var err error
-		newVersion.Build, err = strconv.Atoi(buildNumStr)
-		if err != nil {
+		newVersion.Build, err = strconv.Atoi(opts.build)
+		if err != nil || newVersion.Build < 0 {
 			// keep calm and carry on
-			fmt.Println("There was an error parsing the build number you provided")
-			os.Exit(1)
+			printError("Build number must be a non-negative integer, got '%s'\n", opts.build)
+			exit(exitFailure)
 		}
+	default:
+		newVersion.Build = promptStartingBuild()
 	}
 
-	if !prompt.ConfirmWithDefault("Are these the correct? (Y/n)", true) {
-		fmt.Println("Aborted")
-		os.Exit(0)
+	if !opts.yes {
+		confirmInit(&newVersion)
 	}
-
 	return &newVersion
 }
 
-// Prints current version object to ver.json
-func printToFile(v *GoVersion) {
-	versionBytes, err := json.MarshalIndent(*v, "", "  ")
+// Number of invalid answers accepted for a single init field before giving
+// up, so that a script feeding the wrong input doesn't loop forever
+const initPromptAttempts int = 3
+
+// Answers to the init prompts when stdin isn't a terminal, one per line in
+// the order the prompts appear: project name, version, version name, build
+// number and confirmation. Fields given as flags are skipped and blank lines
+// take the default.
+var pipedAnswers *bufio.Scanner
+
+// Next piped answer, exiting at the end of the input if the field has no
+// default
+func pipedAnswer(field string, required bool) (string, bool) {
+	if pipedAnswers.Scan() {
+		return strings.TrimSpace(pipedAnswers.Text()), true
+	}
+	if !required {
+		return "", false
+	}
+	printError("Not enough input for non-interactive init, no answer for the %s\n", field)
+	fmt.Fprintln(os.Stderr, "Answers are read one per line: project name, version, version name, build number and confirmation")
+	fmt.Fprintln(os.Stderr, "Fields can be given as flags instead, see `gover help init`")
+	exit(exitUsage)
+	return "", false
+}
+
+// A piped answer that isn't valid can't be asked for again
+func invalidPipedAnswer(format string, args ...interface{}) {
+	printError(format, args...)
+	exit(exitUsage)
+}
+
+func promptProjectName() string {
+	if pipedAnswers != nil {
+		name, _ := pipedAnswer("project name", true)
+		if name == "" {
+			invalidPipedAnswer("Project name can't be empty\n")
+		}
+		return name
+	}
+	return strings.TrimSpace(prompt.StringRequired("Project name (required)"))
+}
+
+func promptInitCodename() string {
+	if pipedAnswers != nil {
+		codename, _ := pipedAnswer("version name", true)
+		if codename == "" {
+			invalidPipedAnswer("Version name can't be empty\n")
+		}
+		return codename
+	}
+	return strings.TrimSpace(prompt.StringRequired("Version name (required)"))
+}
+
+// Asks for a field that can be left out by pressing enter
+func promptOptional(field string) string {
+	return strings.TrimSpace(prompt.String(field + " (optional)"))
+}
+
+// Asks for the starting version until the answer parses, an empty answer
+// means fallback
+func promptStartingVersion(fallback *semver.Version) *semver.Version {
+	if pipedAnswers != nil {
+		answer, _ := pipedAnswer("version", false)
+		if answer == "" {
+			return fallback
+		}
+		v, err := parseVersionInput(answer)
+		if err != nil {
+			invalidPipedAnswer("'%s' is not a valid semver version: %s\n", answer, err)
+		}
+		return v
+	}
+
+	for attempt := 0; attempt < initPromptAttempts; attempt++ {
+		answer := strings.TrimSpace(prompt.String(fmt.Sprintf("Current version (default=%s)", fallback)))
+		if answer == "" {
+			return fallback
+		}
+		v, err := parseVersionInput(answer)
+		if err == nil {
+			return v
+		}
+		fmt.Printf("'%s' is not a valid semver version: %s\n", answer, err)
+	}
+	printError("Too many invalid versions, giving up\n")
+	exit(exitFailure)
+	return nil
+}
+
+// Asks for the starting build number until the answer is a non-negative
+// integer, an empty answer means defaultBuild
+func promptStartingBuild() int {
+	if pipedAnswers != nil {
+		answer, _ := pipedAnswer("build number", false)
+		if answer == "" {
+			return defaultBuild
+		}
+		build, err := strconv.Atoi(answer)
+		if err != nil || build < 0 {
+			invalidPipedAnswer("'%s' is not a non-negative integer\n", answer)
+		}
+		return build
+	}
+
+	for attempt := 0; attempt < initPromptAttempts; attempt++ {
+		answer := strings.TrimSpace(prompt.String(fmt.Sprintf("Current build number (default=%d)", defaultBuild)))
+		if answer == "" {
+			return defaultBuild
+		}
+		build, err := strconv.Atoi(answer)
+		if err == nil && build >= 0 {
+			return build
+		}
+		fmt.Printf("'%s' is not a non-negative integer\n", answer)
+	}
+	printError("Too many invalid build numbers, giving up\n")
+	exit(exitFailure)
+	return 0
+}
+
+// Shows the answers and asks for confirmation. Answering no offers to change
+// one of the fields and asks again, rather than starting over.
+func confirmInit(v *version.GoVersion) {
+	fields := []string{"Project name", "Version", "Version name", "Build number", "Author", "Description", "Abort"}
+	for {
+		if textVersionFile() {
+			fmt.Printf("\n  Version:      %s\n\n", v.Version)
+		} else {
+			fmt.Printf("\n  Project name: %s\n  Version:      %s\n  Version name: %s\n  Build number: %d\n", v.ProjectName, v.Version, v.VersionString, v.Build)
+			if v.Author != "" {
+				fmt.Printf("  Author:       %s\n", v.Author)
+			}
+			if v.Description != "" {
+				fmt.Printf("  Description:  %s\n", v.Description)
+			}
+			fmt.Println()
+		}
+		if pipedAnswers != nil {
+			confirmPipedInit()
+			return
+		}
+		if prompt.ConfirmWithDefault("Is this correct? (Y/n)", true) {
+			return
+		}
+
+		if textVersionFile() {
+			if !prompt.ConfirmWithDefault("Change the version? (Y/n)", true) {
+				fmt.Fprintln(os.Stderr, "Aborted")
+				exit(exitSuccess)
+			}
+			v.Version = promptStartingVersion(defaultVersion)
+			continue
+		}
+		switch prompt.Choose("Which field do you want to change?", fields) {
+		case 0:
+			v.ProjectName = promptProjectName()
+		case 1:
+			v.Version = promptStartingVersion(defaultVersion)
+		case 2:
+			v.VersionString = promptInitCodename()
+		case 3:
+			v.Build = promptStartingBuild()
+		case 4:
+			v.Author = promptOptional("Author")
+		case 5:
+			v.Description = promptOptional("Description")
+		default:
+			fmt.Fprintln(os.Stderr, "Aborted")
+			exit(exitSuccess)
+		}
+	}
+}
+
+// Piped input can't go back and change a field, so anything but yes aborts
+func confirmPipedInit() {
+	answer, _ := pipedAnswer("confirmation", true)
+	switch strings.ToLower(answer) {
+	case "", "y", "yes":
+		return
+	case "n", "no":
+		fmt.Fprintln(os.Stderr, "Aborted")
+		exit(exitSuccess)
+	}
+	invalidPipedAnswer("Confirmation must be y or n, got '%s'\n", answer)
+}
+
+// Current time for the version file timestamps. SOURCE_DATE_EPOCH replaces
+// it for reproducible builds (https://reproducible-builds.org/specs/source-date-epoch/).
+func now() time.Time {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Now()
+	}
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
 	if err != nil {
-		fmt.Println("ERROR: Unable to marshal version object")
-		fmt.Println(err)
-		os.Exit(1)
+		printError("SOURCE_DATE_EPOCH must be a number of seconds, got '%s'\n", epoch)
+		exit(exitUsage)
 	}
+	return time.Unix(seconds, 0)
+}
 
-	err = os.Rename(versionFileName, versionFileName+".bak")
-	if !os.IsNotExist(err) && err != nil {
-		fmt.Println("ERROR: Unable to create backup version file, aborting")
-		fmt.Printf("Is there already a %s.bak file in your root directory?", versionFileName)
-		fmt.Println(err)
-		os.Exit(1)
+// Prints current version object to the version file
+func printToFile(v *version.GoVersion) {
+	if textVersionFile() {
+		if v.VersionString != "" {
+			printWarning("%s only stores the version, the codename '%s' is not saved\n", filepath.Base(versionFile), v.VersionString)
+		}
+		if v.Build != 0 && settings.BuildSource != version.BuildSourceGitCount {
+			printWarning("%s only stores the version, build %d is not saved\n", filepath.Base(versionFile), v.Build)
+		}
+	}
+	if modifiedOutside {
+		// Sealing now would hide the changes the checksum caught
+		refuseModifiedFile()
 	}
+	v.Touch(now())
+	if !textVersionFile() && (settings.Integrity || v.Checksum != "") {
+		if err := v.Seal(); err != nil {
+			printError("Unable to compute the version file checksum\n")
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitFailure)
+		}
+	}
+	var err error
+	switch {
+	case stdioMode:
+		err = writeStdoutVersion(v)
+	case noBackup || settings.NoBackup:
+		err = version.Save(versionFile, v)
+	case settings.BackupRetention > 0:
+		err = version.SaveWithBackups(versionFile, v, settings.BackupRetention, time.Now())
+	case settings.Backup:
+		err = version.SaveWithBackup(versionFile, v)
+	default:
+		err = version.Save(versionFile, v)
+	}
+	if err != nil {
+		printError("Unable to write the version file\n")
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+}
 
-	verFile, err := os.Create(versionFileName)
+// Attaches metadata to the version, an empty string clears it
+func setMetadata(v *version.GoVersion, metadata string) *version.GoVersion {
+	if err := v.SetMetadata(metadata); err != nil {
+		printError("'%s' is not valid semver build metadata\n", metadata)
+		exit(exitUsage)
+	}
+	return v
+}
+
+func setCodename(v *version.GoVersion, codename string) *version.GoVersion {
+	codename = strings.TrimSpace(codename)
+	if codename == "" {
+		printError("Codename can't be empty\n")
+		exit(exitUsage)
+	}
+	v.VersionString = codename
+	return v
+}
+
+// Asks for a new codename, keeping the current one when the answer is empty
+// or there's no terminal to ask on
+func promptCodename(v *version.GoVersion) *version.GoVersion {
+	if !stdinIsTerminal() {
+		logVerbose("stdin is not a terminal, keeping codename %s", v.VersionString)
+		return v
+	}
+
+	codename := prompt.String(fmt.Sprintf("Codename (default=%s)", v.VersionString))
+	if strings.TrimSpace(codename) == "" {
+		return v
+	}
+	return setCodename(v, codename)
+}
+
+// Asks before a bump is written, exiting when the answer is no. Piped input
+// can't answer, so there --yes is required.
+func confirmBump(before *version.GoVersion, v *version.GoVersion) {
+	if !stdinIsTerminal() {
+		printError("Bumps need confirmation and stdin is not a terminal, use --yes to confirm\n")
+		exit(exitUsage)
+	}
+	if !prompt.ConfirmWithDefault(fmt.Sprintf("%s → %s, proceed? (y/N)", before.Version, v.Version), false) {
+		fmt.Fprintln(os.Stderr, "Aborted")
+		exit(exitSuccess)
+	}
+}
+
+func knownLevel(level string) bool {
+	for _, l := range version.Levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// Asks which level to bump, listing the version each one leads to. Only
+// called with a terminal on stdin.
+func promptBumpLevel(v *version.GoVersion) string {
+	levels := []string{"patch", "minor", "major"}
+	var choices []string
+	for _, level := range levels {
+		next := v.Clone()
+		if err := next.Bump(level); err != nil {
+			printError("Unable to bump %s version\n", level)
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitFailure)
+		}
+		choices = append(choices, fmt.Sprintf("%s → %s", level, next.Version))
+	}
+	choices = append(choices, "Abort")
+
+	choice := prompt.Choose(fmt.Sprintf("Current version is %s, which level do you want to bump?", v.Version), choices)
+	if choice < 0 || choice >= len(levels) {
+		fmt.Fprintln(os.Stderr, "Aborted")
+		exit(exitSuccess)
+	}
+	return levels[choice]
+}
+
+// Parses text as a text/template, exiting with the offending text on failure
+func parseTemplate(name string, text string) *template.Template {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
 	if err != nil {
-		fmt.Println("ERROR: Unable to create new version file")
-		fmt.Println(err)
-		os.Exit(1)
+		printError("Unable to parse %s template '%s'\n", name, text)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitUsage)
+	}
+	return tmpl
+}
+
+func renderTemplate(tmpl *template.Template, v *version.GoVersion) string {
+	var out strings.Builder
+	if err := tmpl.Execute(&out, version.NewTemplateData(v, settings.ValuePrefix())); err != nil {
+		printError("Unable to render %s template '%s'\n", tmpl.Name(), tmpl.Root.String())
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitUsage)
+	}
+	return out.String()
+}
+
+// Parses the --format template and renders it once against v so that bad
+// field references are reported before anything is written
+func prepareOutputFormat(v *version.GoVersion) {
+	if outputFormat == "" {
+		return
+	}
+	outputTemplate = parseTemplate("format", outputFormat)
+	renderTemplate(outputTemplate, v)
+}
+
+func checkOutputFlags() {
+	if jsonOutput && outputFormat != "" {
+		printError("--json and --format can't be used together\n")
+		exit(exitUsage)
+	}
+	if quietOutput && (jsonOutput || outputFormat != "") {
+		printError("--quiet can't be used with --json or --format\n")
+		exit(exitUsage)
+	}
+	if quietOutput {
+		os.Stdout = os.Stderr
+	}
+	if ghaOutput {
+		requireGitHubOutput()
+	}
+}
+
+// Prints informational messages that aren't the requested output, which go
+// to stderr when stdout is reserved for JSON or --format output
+func printInfo(format string, args ...interface{}) {
+	fmt.Fprintf(infoOutput(), format, args...)
+}
+
+func infoOutput() io.Writer {
+	if jsonOutput || outputFormat != "" {
+		return os.Stderr
 	}
+	return os.Stdout
+}
 
-	_, err = verFile.Write(versionBytes)
+func printJSON(value interface{}) {
+	out, err := json.Marshal(value)
 	if err != nil {
-		fmt.Println("ERROR: There was an error writing to the version file, restoring from backup")
-		fmt.Println(err)
+		printError("Unable to marshal JSON output\n")
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	fmt.Fprintln(stdout, string(out))
+}
+
+// JSON output of the bump commands
+type bumpInfo struct {
+	Previous              *semver.Version   `json:"previous"`
+	Current               *semver.Version   `json:"current"`
+	ProjectName           string            `json:"name"`
+	PreviousVersionString string            `json:"previousVersionString"`
+	VersionString         string            `json:"versionString"`
+	PreviousBuild         int               `json:"previousBuild"`
+	Build                 int               `json:"build"`
+	Commit                string            `json:"commit,omitempty"`
+	Author                string            `json:"author,omitempty"`
+	Description           string            `json:"description,omitempty"`
+	Extras                map[string]string `json:"extras,omitempty"`
+}
 
-		mvErr := os.Rename(versionFileName+".bak", versionFileName)
-		if mvErr != nil {
-			fmt.Printf("ERROR: Could not restore backup. Does %s.bak still exist?\n", versionFileName)
-			fmt.Println(err)
+// Prints the outcome of a bump: the transition from before to v, or with
+// --show-previous and --quiet both versions separated by a space
+func printBumpInfo(before *version.GoVersion, v *version.GoVersion) {
+	switch {
+	case quietOutput && showPrevious:
+		if ghaOutput {
+			writeGitHubOutputs(before, v)
+		}
+		fmt.Fprintln(stdout, settings.ValuePrefix()+before.Version.String(), settings.ValuePrefix()+v.Version.String())
+	case jsonOutput:
+		if ghaOutput {
+			writeGitHubOutputs(before, v)
+		}
+		printJSON(bumpInfo{
+			Previous:              before.Version,
+			Current:               v.Version,
+			ProjectName:           v.ProjectName,
+			PreviousVersionString: before.VersionString,
+			VersionString:         v.VersionString,
+			PreviousBuild:         before.Build,
+			Build:                 v.Build,
+			Commit:                v.Commit,
+			Author:                v.Author,
+			Description:           v.Description,
+			Extras:                v.Extras,
+		})
+	case quietOutput || outputTemplate != nil:
+		printVersionChange(before, v)
+	default:
+		if ghaOutput {
+			writeGitHubOutputs(before, v)
+		}
+		if projectLabel != "" {
+			fmt.Fprintf(stdout, "[%s] ", projectLabel)
 		}
-		os.Exit(1)
+		fmt.Fprintln(stdout, formatBumpTransition(stdout, before, v))
 	}
+}
 
-	err = os.Remove(versionFileName + ".bak")
-	if !os.IsNotExist(err) && err != nil {
-		fmt.Println("ERROR: Unable to remove temporary backup")
-		fmt.Println(err)
-		os.Exit(1)
+// "name: v1.2.3 -> v1.3.0 (build 41 -> 42)", with the codename and build in
+// parentheses when they changed
+func formatBumpTransition(w io.Writer, before *version.GoVersion, v *version.GoVersion) string {
+	transition := fmt.Sprintf("%s: %s -> %s", v.ProjectName, displayVersion(before.Version), colorize(w, colorGreen, displayVersion(v.Version)))
+	var changes []string
+	if before.VersionString != v.VersionString {
+		changes = append(changes, fmt.Sprintf("codename %s -> %s", before.VersionString, colorize(w, colorCyan, v.VersionString)))
+	}
+	if before.Build != v.Build {
+		changes = append(changes, fmt.Sprintf("build %d -> %d", before.Build, v.Build))
+	}
+	if len(changes) > 0 {
+		transition += " (" + strings.Join(changes, ", ") + ")"
 	}
+	return transition
 }
 
-func incrementMajorVersion(v *GoVersion) *GoVersion {
-	newV := v.Version.IncMajor()
-	v.Version = &newV
-	return v
+func printVersionInfo(v *version.GoVersion) {
+	printVersionChange(nil, v)
 }
 
-func incrementMinorVersion(v *GoVersion) *GoVersion {
-	newV := v.Version.IncMinor()
-	v.Version = &newV
-	return v
+// Prints v like printVersionInfo, highlighting what changed since before in
+// the default output
+func printVersionChange(before *version.GoVersion, v *version.GoVersion) {
+	if ghaOutput {
+		writeGitHubOutputs(before, v)
+	}
+	if quietOutput {
+		fmt.Fprintln(stdout, settings.ValuePrefix()+v.Version.String())
+		return
+	}
+	if jsonOutput {
+		printJSON(v)
+		return
+	}
+	if outputTemplate != nil {
+		fmt.Fprintln(stdout, renderTemplate(outputTemplate, v))
+		return
+	}
+	if projectLabel != "" {
+		fmt.Fprintf(stdout, "[%s] ", projectLabel)
+	}
+	fmt.Fprintln(stdout, styleVersionInfo(stdout, before, v))
+}
+
+// Version as shown in text output, with the versionPrefix
+func displayVersion(sv *semver.Version) string {
+	// Only a file loaded with --no-validate can be missing its version
+	if sv == nil {
+		return "no version"
+	}
+	return settings.DisplayPrefix() + sv.String()
+}
+
+// Exits 1 before a command that works from the version reaches a file
+// loaded with --no-validate that has none
+func requireVersion(v *version.GoVersion, action string) {
+	if v.Version == nil {
+		printError("Unable to %s %s\n", action, versionFile)
+		fmt.Fprintln(os.Stderr, version.ErrNoVersion)
+		exit(exitFailure)
+	}
+}
+
+func sameVersion(a, b *semver.Version) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.String() == b.String()
+}
+
+func formatVersionInfo(v *version.GoVersion) string {
+	return styleVersionInfo(nil, nil, v)
+}
+
+// Default text output of a version, colored when w takes colors. Without
+// before, the version and codename stand out. With it, fields that changed
+// are green and the others dimmed.
+func styleVersionInfo(w io.Writer, before *version.GoVersion, v *version.GoVersion) string {
+	field := func(text string, changed bool, color string) string {
+		switch {
+		case before != nil && changed:
+			return colorize(w, colorGreen, text)
+		case before != nil:
+			return colorize(w, colorDim, text)
+		case color != "":
+			return colorize(w, color, text)
+		}
+		return text
+	}
+
+	info := field(v.ProjectName, before != nil && before.ProjectName != v.ProjectName, "") + " -"
+	// Text version files have no codename
+	if v.VersionString != "" {
+		info += " " + field(v.VersionString, before != nil && before.VersionString != v.VersionString, colorCyan)
+	}
+	info += fmt.Sprintf(" %s %s",
+		field(displayVersion(v.Version), before != nil && !sameVersion(before.Version, v.Version), colorGreen),
+		field(fmt.Sprintf("build %d", v.Build), before != nil && before.Build != v.Build, ""),
+	)
+	if v.Commit != "" {
+		info += " " + field("commit "+v.Commit, before != nil && before.Commit != v.Commit, "")
+	}
+	if verbose && v.Author != "" {
+		info += " " + field("author "+v.Author, before != nil && before.Author != v.Author, "")
+	}
+	if verbose && v.Description != "" {
+		info += " " + field(fmt.Sprintf("description %q", v.Description), before != nil && before.Description != v.Description, "")
+	}
+	if verbose && v.UpdatedAt != nil {
+		updated := v.UpdatedAt.Format(time.RFC3339)
+		info += " " + field("updated "+updated, before == nil || before.UpdatedAt == nil || !before.UpdatedAt.Equal(*v.UpdatedAt), "")
+	}
+	return info
+}
+
+// Describes what a mutating command would have written. Machine readable
+// output is still printed so that --dry-run can be used for previews.
+func printDryRun(before *version.GoVersion, after *version.GoVersion) bool {
+	w := infoOutput()
+	printInfo("Before: %s\n", colorize(w, colorDim, formatVersionInfo(before)))
+	printInfo("After:  %s\n", styleVersionInfo(w, before, after))
+	printInfo("Dry run, no changes were written to %s\n", versionFile)
+	return quietOutput || jsonOutput || outputTemplate != nil
+}
+
+// Fields available to `gover get`, in the order `gover get all` prints them
+var getFields = []string{"version", "name", "codename", "build", "commit", "author", "description"}
+
+// Fields `gover set-field` can change, the others have their own commands
+var settableFields = []string{"author", "description"}
+
+func getField(v *version.GoVersion, field string) (string, bool) {
+	switch field {
+	case "version":
+		return settings.ValuePrefix() + v.Version.String(), true
+	case "name":
+		return v.ProjectName, true
+	case "codename":
+		return v.VersionString, true
+	case "build":
+		return strconv.Itoa(v.Build), true
+	case "commit":
+		return v.Commit, true
+	case "author":
+		return v.Author, true
+	case "description":
+		return v.Description, true
+	}
+	return "", false
+}
+
+func printField(v *version.GoVersion, field string) {
+	if field == "all" {
+		for _, f := range getFields {
+			value, _ := getField(v, f)
+			fmt.Fprintf(stdout, "%s=%s\n", f, value)
+		}
+		return
+	}
+
+	value, ok := getField(v, field)
+	if !ok {
+		printError("Unknown field '%s', valid fields are: %s, all\n", field, strings.Join(getFields, ", "))
+		exit(exitUsage)
+	}
+	fmt.Fprintln(stdout, value)
+}
+
+// Single quotes the value for a POSIX shell, nothing inside single quotes is
+// special except the closing quote itself
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// Parses a version given by the user or imported from another file. With
+// --loose or the looseVersions setting a version missing numbers is
+// accepted, saying what was assumed.
+func parseVersionInput(input string) (*semver.Version, error) {
+	if !looseVersions && !settings.LooseVersions {
+		v, err := version.ParseVersion(input)
+		if errors.Is(err, version.ErrPartialVersion) {
+			err = fmt.Errorf("%w, pass --loose to fill in the missing numbers with 0", err)
+		}
+		return v, err
+	}
+	v, assumed, err := version.CoerceVersion(input)
+	if assumed {
+		printInfo("Interpreting '%s' as %s\n", strings.TrimSpace(input), v)
+	}
+	return v, err
+}
+
+// Prints an export line per field so that `eval "$(gover env)"` is safe
+func printEnv(v *version.GoVersion, prefix string) {
+	for _, field := range getFields {
+		value, _ := getField(v, field)
+		fmt.Fprintf(stdout, "export %s%s=%s\n", prefix, strings.ToUpper(field), shellQuote(value))
+	}
+}
+
+func logVerbose(format string, args ...interface{}) {
+	if verbose {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
+
+// Changes made upgrading the loaded version file to the current schema
+var schemaChanges []string
+
+// Set by loadForUpdate, a file whose checksum doesn't match isn't loaded
+// for a command that would write it
+var updatingVersionFile bool
+
+// Set when a file whose checksum doesn't match was loaded anyway, to read it
+var modifiedOutside bool
+
+// Exits rather than writing, and re-sealing, a file changed outside of gover
+// that --accept-changes hasn't approved
+func refuseModifiedFile() {
+	printError("%s was modified outside of gover, refusing to write it\n", versionFile)
+	fmt.Fprintln(os.Stderr, "Check the changes and run with --accept-changes to re-seal it")
+	exit(exitFailure)
 }
 
-func incrementPatchVersion(v *GoVersion) *GoVersion {
-	newV := v.Version.IncPatch()
-	v.Version = &newV
+func loadVersionInfo() *version.GoVersion {
+	var v *version.GoVersion
+	var contents []byte
+	var err error
+	if stdioMode {
+		v, contents, err = readStdinVersion()
+	} else {
+		v, err = version.Load(versionFile)
+		if err != nil {
+			if restored := recoverFromBackup(err); restored != nil {
+				v, err = restored, nil
+			}
+		}
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		printError("Could not find %s file\n", versionFile)
+		fmt.Fprintln(os.Stderr, "Run `gover init` to create it, or choose another file with --file")
+		exit(exitNotInitialized)
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		printError("Unable to read %s file\n", versionFile)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	if errors.Is(err, version.ErrNewerSchema) {
+		printError("%s was written by a newer gover, please upgrade gover to use it\n", versionFile)
+		fmt.Fprintln(os.Stderr, errors.Unwrap(err))
+		exit(exitFailure)
+	}
+	if err != nil {
+		printError("Unable to parse %s file\n", versionFile)
+		fmt.Fprintln(os.Stderr, errors.Unwrap(err))
+		exit(exitFailure)
+	}
+	if !stdioMode {
+		// Read again rather than threading the bytes out of Load, the file
+		// may also have just been restored from a backup
+		contents, _ = os.ReadFile(versionFile)
+	}
+	reportUnknownFields(contents)
+
+	// The checksum is over the file as written, so it's verified before
+	// anything changes in memory
+	if err := v.VerifyChecksum(); err != nil {
+		if !errors.Is(err, version.ErrChecksumMismatch) {
+			printError("Unable to verify the %s checksum\n", versionFile)
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitFailure)
+		}
+		switch {
+		case acceptChanges:
+			resealVersionFile(v)
+		case updatingVersionFile && !dryRun:
+			refuseModifiedFile()
+		default:
+			modifiedOutside = true
+			printWarning("%s was modified outside of gover, check the changes and run with --accept-changes to re-seal it\n", versionFile)
+		}
+	}
+
+	var invalid *invalidVersionError
+	if err := prepareVersion(v); errors.As(err, &invalid) {
+		printError("%s is invalid: %s\n", versionFile, strings.Join(invalid.problems, ", "))
+		fmt.Fprintln(os.Stderr, "Fix it by hand, or run with --no-validate to load it anyway")
+		exit(exitFailure)
+	} else if err != nil {
+		printError("Unable to load %s\n", versionFile)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
 	return v
 }
 
-func printVersionInfo(v *GoVersion) {
-	fmt.Printf("%s - %s v%s build %d\n", v.ProjectName, v.VersionString, v.Version.String(), v.Build)
+// What Validate found wrong with a loaded version file
+type invalidVersionError struct {
+	problems []string
+}
+
+func (e *invalidVersionError) Error() string {
+	return "version file is invalid: " + strings.Join(e.problems, ", ")
+}
+
+// The part of loading a version file that serve's handlers share with
+// loadVersionInfo: the upgrade to the current schema, the settings,
+// validation and the build from buildSource. Errors are returned rather than
+// exiting, which a handler's goroutine can't do.
+func prepareVersion(v *version.GoVersion) error {
+	// Older files are upgraded in memory and rewritten in the current
+	// schema the next time they're saved. Text files have no schema.
+	if !textVersionFile() {
+		changes, err := v.Migrate()
+		if err != nil {
+			return err
+		}
+		schemaChanges = changes
+		for _, change := range schemaChanges {
+			logVerbose("Upgrading %s: %s", versionFile, change)
+		}
+	}
+
+	settings = config.Settings.Override(v.Settings)
+	nameTextProject(versionFile, v)
+	if problems := v.Validate(); len(problems) > 0 && !noValidate {
+		return &invalidVersionError{problems: problems}
+	}
+	return refreshBuild(v)
 }
 
-func loadVersionInfo() *GoVersion {
-	verFile, err := os.Open(versionFileName)
+// Warns about keys in the version file that gover doesn't know, most likely
+// typos, which are lost the next time it's written, or exits with
+// --strict-fields
+func reportUnknownFields(contents []byte) {
+	if textVersionFile() || contents == nil {
+		return
+	}
+	unknown, err := version.UnknownFields(contents, version.FormatFor(versionFile))
 	if err != nil {
-		fmt.Printf("ERROR: Could not find %s file\n", versionFileName)
-		fmt.Println("\nHave you run `gover init` ?\n")
-		os.Exit(1)
+		logVerbose("Unable to look for unknown fields in %s: %s", versionFile, err)
+		return
 	}
+	if len(unknown) == 0 {
+		return
+	}
+	if strictFields {
+		printError("%s has fields gover doesn't know: %s\n", versionFile, strings.Join(unknown, ", "))
+		fmt.Fprintln(os.Stderr, "Check them for typos, or run without --strict-fields to load it anyway")
+		exit(exitFailure)
+	}
+	printWarning("%s has fields gover doesn't know, they'll be dropped the next time it's written: %s\n", versionFile, strings.Join(unknown, ", "))
+}
 
-	var version GoVersion
-	decoder := json.NewDecoder(verFile)
-	err = decoder.Decode(&version)
+// Updates the checksum of a file edited outside of gover to match its
+// contents, leaving everything else as it is
+func resealVersionFile(v *version.GoVersion) {
+	if stdioMode {
+		// Nothing is written back, the new checksum goes out with the JSON
+		if err := v.Seal(); err != nil {
+			printError("Unable to re-seal %s\n", versionFile)
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitFailure)
+		}
+		return
+	}
+	if dryRun {
+		printInfo("Dry run, %s was not re-sealed\n", versionFile)
+		return
+	}
+	if heldLock == nil {
+		acquireLock()
+		defer releaseLock()
+	}
+	err := v.Seal()
+	if err == nil {
+		err = version.Save(versionFile, v)
+	}
 	if err != nil {
-		fmt.Printf("ERROR: Unable to parse %s file\n", versionFileName)
-		fmt.Println(err)
-		os.Exit(1)
+		printError("Unable to re-seal %s\n", versionFile)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
 	}
+	printInfo("Accepted the changes to %s and re-sealed it\n", versionFile)
+}
 
-	return &version
+// Stores the commit the version is cut from. With --commit that's the parent
+// of the bump commit, since the hash is taken before committing.
+func recordCommit(v *version.GoVersion) {
+	hash, err := gitCommitHash()
+	if err != nil {
+		printWarning("Unable to read the git commit, leaving it empty: %s\n", err)
+	}
+	v.Commit = hash
+}
+
+// Replaces the stored build number with the commit count when the version
+// file asks for it. The stored number is kept, with a warning, whenever the
+// count isn't available. An unknown buildSource is an error.
+func refreshBuild(v *version.GoVersion) error {
+	switch settings.BuildSource {
+	case "", version.BuildSourceManual:
+		return nil
+	case version.BuildSourceGitCount:
+	default:
+		return fmt.Errorf("unknown buildSource '%s', valid sources are: %s", settings.BuildSource, strings.Join(version.BuildSources, ", "))
+	}
+
+	count, err := gitCommitCount()
+	if err != nil {
+		printWarning("Unable to count commits, using the stored build %d: %s\n", v.Build, err)
+		return nil
+	}
+	if count != v.Build {
+		logVerbose("Build %d -> %d from git commit count", v.Build, count)
+	}
+	v.Build = count
+	return nil
 }
 
 func main() {
+	defer handleExit()
+	flag.StringVar(&versionFile, "file", versionFileName, "path to the version file")
+	flag.StringVar(&versionFile, "f", versionFileName, "path to the version file (shorthand)")
+	flag.StringVar(&projectSelector, "project", "", "directory or name of the project to act on, for repositories with several")
+	flag.BoolVar(&verbose, "verbose", false, "print diagnostic information to stderr")
+	flag.BoolVar(&verbose, "v", false, "print diagnostic information to stderr (shorthand)")
+	flag.StringVar(&outputFormat, "format", "", "text/template used to print the version, e.g. '{{.ProjectName}}-{{.Version}}'")
+	flag.BoolVar(&jsonOutput, "json", false, "print version information as JSON")
+	flag.BoolVar(&dryRun, "dry-run", false, "show what would change without writing the version file")
+	flag.BoolVar(&noBackup, "no-backup", false, "don't back up the version file before writing it")
+	flag.BoolVar(&acceptChanges, "accept-changes", false, "re-seal a version file whose checksum no longer matches")
+	flag.BoolVar(&recoverBackup, "recover", false, "restore a missing or unparseable version file from its newest backup without asking")
+	flag.BoolVar(&noValidate, "no-validate", false, "load a version file even when its version, name or build is invalid")
+	flag.BoolVar(&strictFields, "strict-fields", false, "exit 1 when the version file has fields gover doesn't know, rather than warning")
+	flag.BoolVar(&looseVersions, "loose", false, "read versions like '1.2' as 1.2.0 in init, set and imports")
+	flag.BoolVar(&stdioMode, "stdio", false, "read the version JSON from stdin and write the changed version to stdout")
+	flag.BoolVar(&ghaOutput, "gha", false, "also write the version to $GITHUB_OUTPUT as GitHub Actions step outputs")
+	flag.BoolVar(&quietOutput, "quiet", false, "print only the version, everything else goes to stderr")
+	flag.BoolVar(&quietOutput, "q", false, "print only the version (shorthand)")
+	flag.DurationVar(&lockTimeout, "lock-timeout", defaultLockTimeout, "how long to wait for another gover process to release the version file")
+	flag.BoolVar(&showVersion, "version", false, "print gover's own version and exit")
+	flag.BoolVar(&noColor, "no-color", false, "don't color output, also turned off by setting NO_COLOR")
+	flag.Usage = func() {
+		printUsage(os.Stderr)
+	}
 	flag.Parse()
-	args := os.Args[1:] // cutting off binary call
+	args := flag.Args()
 
-	if len(args) == 0 {
-		v := loadVersionInfo()
-		printVersionInfo(v)
-		os.Exit(0)
+	name := ""
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	cmd, ok := findCommand(name)
+	if !ok {
+		runPlugin(name, args)
+		unknownCommand(name)
 	}
 
-	if args[0] == "init" {
-		v := initialize()
-		printToFile(v)
+	// Global flags are accepted after the command name too
+	cmdFlags := flag.NewFlagSet(cmd.name, flag.ExitOnError)
+	run := cmd.setup(cmdFlags)
+	global := addGlobalFlags(cmdFlags)
+	cmdFlags.Usage = func() {
+		commandUsage(os.Stderr, cmd, cmdFlags, global)
+	}
+	args = parseInterspersed(cmdFlags, args)
+
+	if showVersion {
+		printGoverVersion()
+		return
+	}
+	if cmd.standalone {
+		run(args)
 		return
 	}
 
-	v := loadVersionInfo()
-	switch args[0] {
-	case "major":
-		v = incrementMajorVersion(v)
-	case "minor":
-		v = incrementMinorVersion(v)
-	case "patch":
-		v = incrementPatchVersion(v)
-	default:
-		fmt.Printf("Unknown command '%s'", args[0])
-		os.Exit(2)
+	if stdioMode {
+		checkStdioFlags(cmd.name, cmdFlags)
+	} else {
+		resolveVersionFile(cmd.name, cmdFlags)
+	}
+
+	// Without a version file to print, a bare `gover` is most likely someone
+	// looking for help. With a backup to restore it from, loading the file
+	// offers that instead.
+	if _, err := os.Stat(versionFile); cmd.name == "" && !explicitFile && !stdioMode && errors.Is(err, fs.ErrNotExist) {
+		if _, err := version.LatestBackup(versionFile); err != nil {
+			printUsage(os.Stderr)
+			exit(exitUsage)
+		}
+	}
+	if cmd.name != "init" {
+		loadConfig()
+	}
+	checkOutputFlags()
+	run(args)
+}
+
+// Sets versionFile from --file, GOVER_FILE or --project, or else by searching
+// the working directory and its parents
+func resolveVersionFile(name string, fs *flag.FlagSet) {
+	// The --file flag takes precedence over GOVER_FILE, and either one turns
+	// off searching for the default file names
+	fileGiven := func(f *flag.Flag) {
+		if f.Name == "file" || f.Name == "f" {
+			explicitFile = true
+		}
+	}
+	flag.Visit(fileGiven)
+	fs.Visit(fileGiven)
+	if envFile := os.Getenv("GOVER_FILE"); envFile != "" && !explicitFile {
+		versionFile = envFile
+		explicitFile = true
+	}
+
+	// init always creates the file in the working directory, everything else
+	// can be run from anywhere inside the project
+	isInit := name == "init"
+	if projectSelector != "" {
+		if explicitFile {
+			printError("--project can't be used with --file or GOVER_FILE\n")
+			exit(exitUsage)
+		}
+		versionFile = selectProject(projectSelector, isInit)
+		explicitFile = !isInit
+		projectLabel = projectSelector
+		if root, ok := projectRoot(); ok {
+			if rel, err := filepath.Rel(root, filepath.Dir(versionFile)); err == nil {
+				projectLabel = filepath.ToSlash(rel)
+			}
+		}
+	} else if !explicitFile && !isInit {
+		if found, ok := findVersionFile(); ok {
+			versionFile = found
+		} else if name != "list" {
+			requireProjectSelection()
+		}
+	}
+
+	// Resolve once so that error messages show exactly which file is in use
+	if absPath, err := filepath.Abs(versionFile); err == nil {
+		versionFile = absPath
 	}
-	printToFile(v)
-	printVersionInfo(v)
+	logVerbose("Using version file %s", versionFile)
 }
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Name of the merge driver in .gitattributes and git config
+const mergeDriverName string = "gover"
+
+// Exit codes for merge, git takes anything but 0 as an unresolved conflict
+const (
+	exitMergeResolved int = 0
+	exitMergeConflict int = 1
+)
+
+// Reads one side of the merge. git's temporary copies have no extension,
+// so the format comes from the path the file has in the repository.
+func loadMergeSide(path string, format version.Format, optional bool) *version.GoVersion {
+	contents, err := os.ReadFile(path)
+	if err == nil && optional && len(contents) == 0 {
+		// git passes an empty ancestor when both sides added the file
+		return nil
+	}
+	if err != nil {
+		printError("Unable to read %s\n", path)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitMergeConflict)
+	}
+	v, err := version.Decode(contents, format)
+	if err != nil {
+		printError("Unable to parse %s\n", path)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitMergeConflict)
+	}
+	return v
+}
+
+// Resolves a conflict in the version file as a git merge driver, writing
+// the result over ours. Exits 1, leaving ours alone so that git reports the
+// conflict, when the sides can't be reconciled.
+func mergeVersionFiles(ancestorPath, oursPath, theirsPath, repoPath string) {
+	if repoPath == "" {
+		repoPath = versionFileName
+	}
+	format := version.FormatFor(repoPath)
+	ancestor := loadMergeSide(ancestorPath, format, true)
+	ours := loadMergeSide(oursPath, format, false)
+	theirs := loadMergeSide(theirsPath, format, false)
+
+	merged, conflicts, err := version.Merge(ancestor, ours, theirs)
+	if err != nil {
+		printError("Unable to merge %s\n", repoPath)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitMergeConflict)
+	}
+	if len(conflicts) > 0 {
+		printError("Unable to merge %s, both sides changed: %s\n", repoPath, strings.Join(conflicts, ", "))
+		exit(exitMergeConflict)
+	}
+
+	contents, err := version.Encode(merged, format)
+	if err == nil {
+		err = os.WriteFile(oursPath, contents, 0644)
+	}
+	if err != nil {
+		printError("Unable to write the merged %s\n", repoPath)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitMergeConflict)
+	}
+	fmt.Fprintf(os.Stderr, "Merged %s at %s build %d\n", repoPath, displayVersion(merged.Version), merged.Build)
+	exit(exitMergeResolved)
+}
+
+// Registers gover as the merge driver for the version file: the driver in
+// the repository's git config, which isn't shared, and the attribute in
+// .gitattributes at the top of the repository, which is meant to be
+// committed
+func installMergeDriver() {
+	requireGitRepo()
+	rel, err := repoRelativeVersionFile()
+	if err != nil {
+		printError("Unable to locate the version file within the repository\n")
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	top, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		printError("Unable to find the top of the repository\n")
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+
+	gitSettings := [][]string{
+		{"merge." + mergeDriverName + ".name", "gover version file merge"},
+		{"merge." + mergeDriverName + ".driver", "gover merge %O %A %B %P"},
+	}
+	attribute := "/" + rel + " merge=" + mergeDriverName
+	attributesPath := filepath.Join(top, ".gitattributes")
+
+	existing, err := os.ReadFile(attributesPath)
+	if err != nil && !os.IsNotExist(err) {
+		printError("Unable to read %s\n", attributesPath)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	hasAttribute := false
+	for _, line := range strings.Split(string(existing), "\n") {
+		hasAttribute = hasAttribute || strings.TrimSpace(line) == attribute
+	}
+
+	if dryRun {
+		for _, setting := range gitSettings {
+			printInfo("Would run: git config %s '%s'\n", setting[0], setting[1])
+		}
+		if !hasAttribute {
+			printInfo("Would add '%s' to %s\n", attribute, attributesPath)
+		}
+		return
+	}
+
+	for _, setting := range gitSettings {
+		if _, err := runGit("config", setting[0], setting[1]); err != nil {
+			printError("Unable to set %s\n", setting[0])
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitFailure)
+		}
+	}
+	printInfo("Configured the %s merge driver in .git/config\n", mergeDriverName)
+
+	if hasAttribute {
+		printInfo("%s already routes %s to the merge driver\n", attributesPath, rel)
+		return
+	}
+	updated := string(existing)
+	if updated != "" && !strings.HasSuffix(updated, "\n") {
+		updated += "\n"
+	}
+	updated += attribute + "\n"
+	if err := os.WriteFile(attributesPath, []byte(updated), 0644); err != nil {
+		printError("Unable to write %s\n", attributesPath)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	printInfo("Added '%s' to %s, commit it to share the attribute\n", attribute, attributesPath)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// How long a webhook gets to answer, bumps shouldn't hang on a slow one
+const notifyTimeout time.Duration = 5 * time.Second
+
+// Pause before the one retry of a failed webhook
+const notifyRetryDelay time.Duration = time.Second
+
+// What notification payload templates are executed against
+type notifyData struct {
+	Project  string
+	Old      string
+	New      string
+	Codename string
+	Build    int
+	Actor    string
+}
+
+// Who made the bump: the CI user when there is one, then git's user.name and
+// the login name
+func notifyActor() string {
+	for _, name := range []string{"GITHUB_ACTOR", "GITLAB_USER_LOGIN", "BUILD_USER_ID"} {
+		if actor := os.Getenv(name); actor != "" {
+			return actor
+		}
+	}
+	if actor, err := runGit("config", "--get", "user.name"); err == nil && actor != "" {
+		return actor
+	}
+	return os.Getenv("USER")
+}
+
+// Posts the bump from before to v to every configured webhook. Failures are
+// warnings, unless strict is set, in which case gover exits 1 once every
+// webhook has been tried. Messages name the webhook by its configured URL,
+// so that secrets expanded into it aren't printed.
+func notifyBump(before, v *version.GoVersion, strict bool) {
+	if len(config.Notifications) == 0 {
+		return
+	}
+
+	data := notifyData{
+		Project:  v.ProjectName,
+		Old:      settings.ValuePrefix() + before.Version.String(),
+		New:      settings.ValuePrefix() + v.Version.String(),
+		Codename: v.VersionString,
+		Build:    v.Build,
+		Actor:    notifyActor(),
+	}
+	client := &http.Client{Timeout: notifyTimeout}
+
+	failed := false
+	for _, notification := range config.Notifications {
+		if err := notify(client, notification, data); err != nil {
+			printWarning("Unable to notify %s: %s\n", notification.URL, err)
+			failed = true
+			continue
+		}
+		logVerbose("Notified %s", notification.URL)
+	}
+	if failed && strict {
+		printError("Notifications failed, %s was already written\n", displayVersion(v.Version))
+		exit(exitFailure)
+	}
+}
+
+func notify(client *http.Client, notification version.Notification, data notifyData) error {
+	if notification.URL == "" {
+		return fmt.Errorf("url is not set")
+	}
+	endpoint, missing := version.ExpandEnv(notification.URL)
+	headers := make(map[string]string, len(notification.Headers))
+	for name, value := range notification.Headers {
+		expanded, unset := version.ExpandEnv(value)
+		headers[name] = expanded
+		missing = append(missing, unset...)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("environment variables not set: %s", strings.Join(missing, ", "))
+	}
+
+	payload, err := notifyPayload(notification.Payload, data)
+	if err != nil {
+		return err
+	}
+
+	err = postWebhook(client, endpoint, headers, payload)
+	if err != nil {
+		logVerbose("Notifying %s failed, retrying once: %s", notification.URL, err)
+		time.Sleep(notifyRetryDelay)
+		err = postWebhook(client, endpoint, headers, payload)
+	}
+	return err
+}
+
+// Renders the payload template, json quotes a value as a JSON string
+func notifyPayload(text string, data notifyData) ([]byte, error) {
+	if text == "" {
+		text = version.DefaultNotificationPayload
+	}
+	funcs := template.FuncMap{
+		"json": func(value interface{}) (string, error) {
+			var encoded bytes.Buffer
+			encoder := json.NewEncoder(&encoded)
+			encoder.SetEscapeHTML(false)
+			err := encoder.Encode(value)
+			return strings.TrimSuffix(encoded.String(), "\n"), err
+		},
+	}
+	tmpl, err := template.New("payload").Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("payload template: %w", err)
+	}
+
+	var payload bytes.Buffer
+	if err := tmpl.Execute(&payload, data); err != nil {
+		return nil, fmt.Errorf("payload template: %w", err)
+	}
+	if !json.Valid(payload.Bytes()) {
+		return nil, fmt.Errorf("payload is not valid JSON: %s", payload.String())
+	}
+	return payload.Bytes(), nil
+}
+
+func postWebhook(client *http.Client, endpoint string, headers map[string]string, payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		// The error would repeat the URL, secrets and all
+		return fmt.Errorf("invalid webhook URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gover")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// Like above, only keep what went wrong rather than the whole URL
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
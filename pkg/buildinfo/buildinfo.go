@@ -0,0 +1,85 @@
+// Package buildinfo is gover's own version, as opposed to the versions it
+// manages. The variables are set when building a release, e.g.
+//
+//	go build -ldflags "-X github.com/subtlepseudonym/gover/pkg/buildinfo.Version=1.2.0
+//		-X github.com/subtlepseudonym/gover/pkg/buildinfo.Commit=$(git rev-parse --short HEAD)
+//		-X github.com/subtlepseudonym/gover/pkg/buildinfo.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Left unset, they fall back to what the Go toolchain recorded in the binary,
+// and then to "devel" and "unknown".
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Set with -ldflags -X at build time
+var (
+	Version string
+	Commit  string
+	Date    string
+)
+
+const (
+	develVersion string = "devel"
+	unknown      string = "unknown"
+)
+
+// Info describes a gover binary
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns the build information of the running binary. A module version
+// is recorded by `go install ...@version`, and the commit and date by builds
+// from a git checkout.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+	}
+
+	if recorded, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && recorded.Main.Version != "" && recorded.Main.Version != "(devel)" {
+			info.Version = recorded.Main.Version
+		}
+		for _, setting := range recorded.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" && len(setting.Value) >= 7 {
+					info.Commit = setting.Value[:7]
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = setting.Value
+				}
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = develVersion
+	}
+	info.Version = strings.TrimPrefix(info.Version, "v")
+	if info.Commit == "" {
+		info.Commit = unknown
+	}
+	if info.Date == "" {
+		info.Date = unknown
+	}
+	return info
+}
+
+// String formats the information on one line, with space separated fields in
+// a fixed order
+func (i Info) String() string {
+	return fmt.Sprintf("gover %s commit %s built %s %s", i.Version, i.Commit, i.Date, i.GoVersion)
+}
@@ -0,0 +1,178 @@
+package version
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+)
+
+var (
+	ErrInvalidCalVer = errors.New("invalid calver")
+	ErrCalVerBump    = errors.New("calendar versions are bumped by date")
+	ErrNotCalVer     = errors.New("not a calendar versioned project")
+	ErrCalVerRelease = errors.New("no newer calendar version for this date")
+)
+
+// Tokens of a CalVer pattern, see https://calver.org. Each one is a whole
+// dot separated segment of the pattern.
+const (
+	calverFullYear  string = "YYYY"
+	calverShortYear string = "YY"
+	calverPadYear   string = "0Y"
+	calverMonth     string = "MM"
+	calverPadMonth  string = "0M"
+	calverWeek      string = "WW"
+	calverPadWeek   string = "0W"
+	calverDay       string = "DD"
+	calverPadDay    string = "0D"
+	calverMicro     string = "MICRO"
+)
+
+// CalVerTokens lists the tokens a CalVer pattern is made of
+var CalVerTokens = []string{
+	calverFullYear, calverShortYear, calverPadYear,
+	calverMonth, calverPadMonth,
+	calverWeek, calverPadWeek,
+	calverDay, calverPadDay,
+	calverMicro,
+}
+
+// CalVer versions a project by release date instead of semver levels. The
+// pattern's segments become the major, minor and patch numbers, missing ones
+// are 0. Semver numbers can't have leading zeroes, so the padded tokens give
+// the same numbers as the plain ones.
+type CalVer struct {
+	// Pattern is up to three dot separated tokens, e.g. "YYYY.0M.MICRO"
+	Pattern string `json:"pattern"`
+	// Timezone is the IANA name of the zone dates are taken in, UTC when
+	// empty
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// Validate reports whether the pattern and time zone can be used
+func (c CalVer) Validate() error {
+	if _, err := c.tokens(); err != nil {
+		return err
+	}
+	_, err := c.location()
+	return err
+}
+
+func (c CalVer) tokens() ([]string, error) {
+	tokens := strings.Split(c.Pattern, ".")
+	if c.Pattern == "" || len(tokens) > 3 {
+		return nil, fmt.Errorf("%w pattern '%s': needs one to three dot separated tokens", ErrInvalidCalVer, c.Pattern)
+	}
+	for i, token := range tokens {
+		known := false
+		for _, t := range CalVerTokens {
+			known = known || t == token
+		}
+		if !known {
+			return nil, fmt.Errorf("%w pattern '%s': unknown token '%s', valid tokens are: %s", ErrInvalidCalVer, c.Pattern, token, strings.Join(CalVerTokens, ", "))
+		}
+		if token == calverMicro && i != len(tokens)-1 {
+			return nil, fmt.Errorf("%w pattern '%s': %s has to be the last token", ErrInvalidCalVer, c.Pattern, calverMicro)
+		}
+	}
+	if tokens[0] == calverMicro {
+		return nil, fmt.Errorf("%w pattern '%s': needs at least one date token", ErrInvalidCalVer, c.Pattern)
+	}
+	return tokens, nil
+}
+
+func (c CalVer) location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone '%s'", ErrInvalidCalVer, c.Timezone)
+	}
+	return loc, nil
+}
+
+// Next returns the version for a release at t following current. MICRO
+// counts up while the date segments stay the same and restarts at 0 when
+// they change. Without MICRO there can only be one release per date.
+func (c CalVer) Next(current *semver.Version, t time.Time) (*semver.Version, error) {
+	tokens, err := c.tokens()
+	if err != nil {
+		return nil, err
+	}
+	loc, err := c.location()
+	if err != nil {
+		return nil, err
+	}
+	t = t.In(loc)
+
+	var segments [3]int64
+	sameDate := current != nil
+	if current != nil {
+		segments = [3]int64{current.Major(), current.Minor(), current.Patch()}
+	}
+	for i, token := range tokens {
+		if token == calverMicro {
+			if sameDate {
+				segments[i]++
+			} else {
+				segments[i] = 0
+			}
+			continue
+		}
+		value := calverValue(token, t)
+		sameDate = sameDate && segments[i] == value
+		segments[i] = value
+	}
+	for i := len(tokens); i < len(segments); i++ {
+		segments[i] = 0
+	}
+	if sameDate && tokens[len(tokens)-1] != calverMicro {
+		return nil, fmt.Errorf("%w: %s has no %s to count more than one release in the same period", ErrCalVerRelease, c.Pattern, calverMicro)
+	}
+
+	next, err := semver.NewVersion(fmt.Sprintf("%d.%d.%d", segments[0], segments[1], segments[2]))
+	if err != nil {
+		return nil, err
+	}
+	// A clock or time zone behind the last release would go backwards
+	if current != nil && !next.GreaterThan(current) {
+		return nil, fmt.Errorf("%w: the version for %s would be %s, which isn't newer than %s", ErrCalVerRelease, t.Format("2006-01-02"), next, current)
+	}
+	return next, nil
+}
+
+func calverValue(token string, t time.Time) int64 {
+	_, week := t.ISOWeek()
+	switch token {
+	case calverFullYear:
+		return int64(t.Year())
+	case calverShortYear, calverPadYear:
+		return int64(t.Year() - 2000)
+	case calverMonth, calverPadMonth:
+		return int64(t.Month())
+	case calverWeek, calverPadWeek:
+		return int64(week)
+	}
+	return int64(t.Day())
+}
+
+// BumpCalVer moves a calendar versioned project to its version for a release
+// at t, dropping any prerelease label and metadata
+func (v *GoVersion) BumpCalVer(t time.Time) error {
+	if v.CalVer == nil {
+		return ErrNotCalVer
+	}
+	if v.Version == nil {
+		return ErrNoVersion
+	}
+	next, err := v.CalVer.Next(v.Version, t)
+	if err != nil {
+		return err
+	}
+	v.Version = next
+	return nil
+}
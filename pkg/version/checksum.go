@@ -0,0 +1,55 @@
+package version
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ChecksumPrefix names the hash a checksum was computed with
+const ChecksumPrefix string = "sha256:"
+
+var ErrChecksumMismatch = errors.New("checksum doesn't match the version file")
+
+// ComputeChecksum hashes the compact JSON encoding of every field but the
+// checksum itself. The encoding follows the struct rather than the file, so
+// key order, whitespace and the file format don't change the result.
+func (v *GoVersion) ComputeChecksum() (string, error) {
+	unsealed := *v
+	unsealed.Checksum = ""
+	canonical, err := json.Marshal(unsealed)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal version object: %w", err)
+	}
+	sum := sha256.Sum256(canonical)
+	return ChecksumPrefix + hex.EncodeToString(sum[:]), nil
+}
+
+// Seal sets the checksum for the current fields
+func (v *GoVersion) Seal() error {
+	checksum, err := v.ComputeChecksum()
+	if err != nil {
+		return err
+	}
+	v.Checksum = checksum
+	return nil
+}
+
+// VerifyChecksum reports whether the fields still match the checksum,
+// returning ErrChecksumMismatch when they don't. Files without a checksum
+// always pass.
+func (v *GoVersion) VerifyChecksum() error {
+	if v.Checksum == "" {
+		return nil
+	}
+	checksum, err := v.ComputeChecksum()
+	if err != nil {
+		return err
+	}
+	if checksum != v.Checksum {
+		return fmt.Errorf("%w: expected %s, the fields hash to %s", ErrChecksumMismatch, v.Checksum, checksum)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package version
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver"
+)
+
+var ErrPartialVersion = errors.New("version is missing its minor or patch number")
+
+var semverPattern = regexp.MustCompile(SemverPattern)
+
+// A version with only a major, or major and minor number, with anything
+// after it
+var partialVersion = regexp.MustCompile(`^v?(0|[1-9]\d*)(?:\.(0|[1-9]\d*))?([-+].*)?$`)
+
+// ParseVersion parses a complete semver version, allowing a leading "v".
+// The semver package on its own would quietly fill in a missing minor or
+// patch number, which ParseVersion refuses with ErrPartialVersion.
+func ParseVersion(input string) (*semver.Version, error) {
+	if partialVersion.MatchString(input) {
+		return nil, fmt.Errorf("%w: '%s'", ErrPartialVersion, input)
+	}
+	if !semverPattern.MatchString(input) {
+		if _, err := semver.NewVersion(input); err != nil {
+			return nil, err
+		}
+		return nil, semver.ErrInvalidSemVer
+	}
+	return semver.NewVersion(input)
+}
+
+// CoerceVersion parses input leniently: surrounding whitespace and a leading
+// "v" are dropped, and a missing minor or patch number is taken to be 0, so
+// that "1.2" is 1.2.0. It reports whether numbers were filled in, anything
+// else that isn't semver is still an error.
+func CoerceVersion(input string) (*semver.Version, bool, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(input), "v")
+	match := partialVersion.FindStringSubmatch(trimmed)
+	if match == nil {
+		v, err := ParseVersion(trimmed)
+		return v, false, err
+	}
+
+	minor := match[2]
+	if minor == "" {
+		minor = "0"
+	}
+	v, err := ParseVersion(fmt.Sprintf("%s.%s.0%s", match[1], minor, match[3]))
+	return v, err == nil, err
+}
@@ -0,0 +1,114 @@
+package version
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ConfigFileNames are the names a config file can have, it's read from the
+// directory holding the version file
+var ConfigFileNames = []string{".gover.yaml", ".gover.yml", ".gover.json", ".gover.toml"}
+
+// Config is the contents of a config file, project configuration that
+// doesn't change as the version does. Command line flags take precedence
+// over the config file, which takes precedence over the built-in defaults.
+type Config struct {
+	Settings
+
+	// Format is the default --format output template
+	Format string `json:"format,omitempty"`
+	// JSON makes JSON the default output
+	JSON bool `json:"json,omitempty"`
+	// SignTags makes gover tag create GPG signed tags
+	SignTags bool `json:"signTags,omitempty"`
+	// CommitMessage is the default --message template for bump commits
+	CommitMessage string `json:"commitMessage,omitempty"`
+	// Notifications are webhooks told about each bump
+	Notifications []Notification `json:"notifications,omitempty"`
+}
+
+// DefaultNotificationPayload is the body posted when a notification has no
+// payload template of its own
+const DefaultNotificationPayload string = `{"project": {{json .Project}}, "old": {{json .Old}}, "new": {{json .New}}, "build": {{.Build}}, "actor": {{json .Actor}}}`
+
+// Notification is a webhook that bumps POST to. URL and header values can
+// reference environment variables as ${NAME}, so that tokens and Slack or
+// Teams webhook secrets don't have to be stored in the config file. Payload
+// is a text/template for the JSON body, DefaultNotificationPayload when
+// empty.
+type Notification struct {
+	URL     string            `json:"url"`
+	Payload string            `json:"payload,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// ExpandEnv replaces ${NAME} references in s with the environment, returning
+// the names of the variables that aren't set
+func ExpandEnv(s string) (string, []string) {
+	var missing []string
+	expanded := os.Expand(s, func(name string) string {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return value
+	})
+	return expanded, missing
+}
+
+// LoadConfig reads the config file at path, choosing the format by
+// extension. Keys that Config doesn't know are returned rather than treated
+// as an error, so that a typo or an option from a newer gover doesn't stop
+// every command.
+func LoadConfig(path string) (*Config, []string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	jsonBytes, err := FormatFor(path).ToJSON(contents)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var config Config
+	if err := json.Unmarshal(jsonBytes, &config); err != nil {
+		return nil, nil, err
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(jsonBytes, &keys); err != nil {
+		return nil, nil, err
+	}
+	known := jsonKeys(reflect.TypeOf(config))
+	var unknown []string
+	for key := range keys {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+
+	return &config, unknown, nil
+}
+
+// Top level JSON keys of a struct type, including those of embedded structs
+func jsonKeys(t reflect.Type) map[string]bool {
+	keys := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for key := range jsonKeys(field.Type) {
+				keys[key] = true
+			}
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			keys[name] = true
+		}
+	}
+	return keys
+}
@@ -0,0 +1,230 @@
+package version
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Load reads the version file at path, choosing the format by extension.
+// Errors from reading the file wrap the underlying os error, so a missing
+// file can be detected with errors.Is(err, os.ErrNotExist).
+func Load(path string) (*GoVersion, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := Decode(contents, FormatFor(path))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+	return v, nil
+}
+
+// Decode parses version file contents in the given format
+func Decode(data []byte, format Format) (*GoVersion, error) {
+	jsonBytes, err := format.ToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var v GoVersion
+	if err := json.Unmarshal(jsonBytes, &v); err != nil {
+		return nil, err
+	}
+	// Fields this gover doesn't know about would be dropped on the next save
+	if err := checkSchema(&v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Encode serializes v in the given format
+func Encode(v *GoVersion, format Format) ([]byte, error) {
+	// Without HTML escaping, so that an author like "Jane <jane@example.com>"
+	// stays readable
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(*v); err != nil {
+		return nil, fmt.Errorf("unable to marshal version object: %w", err)
+	}
+	jsonBytes := bytes.TrimSuffix(encoded.Bytes(), []byte("\n"))
+
+	data, err := format.FromJSON(jsonBytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode version object as %s: %w", format.Name, err)
+	}
+	return data, nil
+}
+
+// Save writes v to path, choosing the format by extension. The new contents
+// go to a temporary file in the same directory that is synced and then
+// renamed over path, so path holds either the old or the new version even if
+// the process dies part way through. An existing file's permissions are kept.
+func Save(path string, v *GoVersion) error {
+	versionBytes, err := Encode(v, FormatFor(path))
+	if err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := createTemp(filepath.Dir(path), filepath.Base(TempFileGlob(path)))
+	if err != nil {
+		return fmt.Errorf("unable to create a temporary file next to %s: %w", path, err)
+	}
+	tmpPath := tmp.Name()
+
+	_, err = tmp.Write(versionBytes)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, mode)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("unable to write temporary file %s, %s was not changed: %w", tmpPath, path, err)
+	}
+
+	if err := replaceFile(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("unable to rename %s to %s, %s was not changed: %w", tmpPath, path, path, err)
+	}
+	return nil
+}
+
+// Replaced in tests to make the temporary file fail
+var createTemp = os.CreateTemp
+
+// TempFileGlob matches the temporary files Save creates for path. One left
+// behind means a write was interrupted.
+func TempFileGlob(path string) string {
+	dir, base := filepath.Split(path)
+	return filepath.Join(dir, "."+base+".tmp-*")
+}
+
+// Renames src over dst. Windows refuses while another process, often a virus
+// scanner or indexer, briefly has dst open, so there the rename is retried
+// for a short while before giving up.
+func replaceFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	for attempt := 0; err != nil && runtime.GOOS == "windows" && attempt < 10; attempt++ {
+		time.Sleep(50 * time.Millisecond)
+		err = os.Rename(src, dst)
+	}
+	return err
+}
+
+// SaveWithBackup copies the existing file at path to path.bak, then saves v
+// as Save does. The backup is written in place rather than renamed, so an
+// existing .bak never gets in the way.
+func SaveWithBackup(path string, v *GoVersion) error {
+	contents, err := os.ReadFile(path)
+	if err == nil {
+		backup := path + ".bak"
+		if err := os.WriteFile(backup, contents, 0644); err != nil {
+			return fmt.Errorf("unable to write backup %s: %w", backup, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("unable to read %s to back it up: %w", path, err)
+	}
+	return Save(path, v)
+}
+
+// BackupTimeFormat is the timestamp SaveWithBackups appends to backup names,
+// e.g. ver.json.bak.20240601T120301. It is always in UTC.
+const BackupTimeFormat string = "20060102T150405"
+
+var backupSuffix = regexp.MustCompile(`^\.bak\.[0-9]{8}T[0-9]{6}$`)
+
+// SaveWithBackups copies the existing file at path to a backup named after
+// t, saves v as Save does and then removes all but the newest retention
+// backups. Saving twice in the same second replaces that second's backup.
+func SaveWithBackups(path string, v *GoVersion, retention int, t time.Time) error {
+	contents, err := os.ReadFile(path)
+	if err == nil {
+		backup := path + ".bak." + t.UTC().Format(BackupTimeFormat)
+		if err := os.WriteFile(backup, contents, 0644); err != nil {
+			return fmt.Errorf("unable to write backup %s: %w", backup, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("unable to read %s to back it up: %w", path, err)
+	}
+	if err := Save(path, v); err != nil {
+		return err
+	}
+
+	backups, err := Backups(path)
+	if err != nil {
+		return err
+	}
+	for i := retention; i < len(backups); i++ {
+		if err := os.Remove(backups[i]); err != nil {
+			return fmt.Errorf("unable to remove old backup %s: %w", backups[i], err)
+		}
+	}
+	return nil
+}
+
+// Backups lists the timestamped backups of path, newest first. Only names
+// exactly matching path.bak.<BackupTimeFormat> are included, so pruning them
+// never touches anything else.
+func Backups(path string) ([]string, error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list backups of %s: %w", path, err)
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, base) || !backupSuffix.MatchString(name[len(base):]) {
+			continue
+		}
+		if _, err := time.Parse(BackupTimeFormat, name[len(base)+len(".bak."):]); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(filepath.Dir(path), name))
+	}
+	// The timestamps sort the same as the times they stand for
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups, nil
+}
+
+// LatestBackup returns the newest timestamped backup of path, or the .bak
+// file SaveWithBackup writes when there are none. The error wraps
+// os.ErrNotExist when there is no backup at all.
+func LatestBackup(path string) (string, error) {
+	backups, err := Backups(path)
+	if err != nil {
+		return "", err
+	}
+	if len(backups) > 0 {
+		return backups[0], nil
+	}
+	if _, err := os.Stat(path + ".bak"); err != nil {
+		return "", err
+	}
+	return path + ".bak", nil
+}
@@ -0,0 +1,318 @@
+package version
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/semver"
+)
+
+func testVersion(t *testing.T, version string) *GoVersion {
+	t.Helper()
+	return &GoVersion{
+		SchemaVersion: CurrentSchema,
+		ProjectName:   "test",
+		Version:       semver.MustParse(version),
+		VersionString: "codename",
+	}
+}
+
+func readVersion(t *testing.T, path string) *GoVersion {
+	t.Helper()
+	v, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load(%s): %s", path, err)
+	}
+	return v
+}
+
+func TestSaveReplacesExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ver.json")
+	if err := Save(path, testVersion(t, "1.0.0")); err != nil {
+		t.Fatalf("first Save: %s", err)
+	}
+	if runtime.GOOS != "windows" {
+		if err := os.Chmod(path, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Renaming over an existing file is the case Windows refuses without
+	// replaceFile
+	if err := Save(path, testVersion(t, "1.1.0")); err != nil {
+		t.Fatalf("second Save: %s", err)
+	}
+	if got := readVersion(t, path).Version.String(); got != "1.1.0" {
+		t.Errorf("version is %s, want 1.1.0", got)
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("mode is %o, want 600", info.Mode().Perm())
+		}
+	}
+	if leftovers, _ := filepath.Glob(TempFileGlob(path)); len(leftovers) > 0 {
+		t.Errorf("temporary files left behind: %v", leftovers)
+	}
+}
+
+func TestSaveFailedWriteKeepsOriginal(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ver.json")
+	if err := Save(path, testVersion(t, "1.0.0")); err != nil {
+		t.Fatal(err)
+	}
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A temporary file that's already closed fails the write
+	createTemp = func(dir, pattern string) (*os.File, error) {
+		f, err := os.CreateTemp(dir, pattern)
+		if err == nil {
+			f.Close()
+		}
+		return f, err
+	}
+	defer func() { createTemp = os.CreateTemp }()
+
+	if err := Save(path, testVersion(t, "2.0.0")); err == nil {
+		t.Fatal("Save succeeded with a failing temporary file")
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != string(original) {
+		t.Errorf("%s changed after a failed write:\n%s", path, contents)
+	}
+	if leftovers, _ := filepath.Glob(TempFileGlob(path)); len(leftovers) > 0 {
+		t.Errorf("temporary files left behind: %v", leftovers)
+	}
+}
+
+func TestSaveWithBackupsNamesAndPrunes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ver.json")
+	start := time.Date(2024, 6, 1, 12, 3, 1, 0, time.UTC)
+
+	versions := []string{"1.0.0", "1.1.0", "1.2.0", "1.3.0"}
+	for i, version := range versions {
+		at := start.Add(time.Duration(i) * time.Minute)
+		if err := SaveWithBackups(path, testVersion(t, version), 2, at); err != nil {
+			t.Fatalf("SaveWithBackups %s: %s", version, err)
+		}
+	}
+
+	backups, err := Backups(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first save had nothing to back up, and only the newest two of the
+	// other three are kept
+	want := []string{path + ".bak.20240601T120601", path + ".bak.20240601T120501"}
+	if len(backups) != len(want) {
+		t.Fatalf("backups are %v, want %v", backups, want)
+	}
+	for i := range want {
+		if backups[i] != want[i] {
+			t.Errorf("backup %d is %s, want %s", i, backups[i], want[i])
+		}
+	}
+	if got := readVersion(t, backups[0]).Version.String(); got != "1.2.0" {
+		t.Errorf("newest backup holds %s, want 1.2.0", got)
+	}
+	if got := readVersion(t, path).Version.String(); got != "1.3.0" {
+		t.Errorf("version is %s, want 1.3.0", got)
+	}
+}
+
+func TestBackupsIgnoresOtherNames(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ver.json")
+	for _, name := range []string{"ver.json.bak", "ver.json.bak.20241301T000000", "ver.json.bak.2024", "other.json.bak.20240601T120301"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups, err := Backups(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) > 0 {
+		t.Errorf("backups are %v, want none", backups)
+	}
+}
+
+func TestLatestBackup(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  string
+	}{
+		{name: "none"},
+		{name: "plain backup", files: []string{"ver.json.bak"}, want: "ver.json.bak"},
+		{
+			name:  "timestamped over plain",
+			files: []string{"ver.json.bak", "ver.json.bak.20240601T120301", "ver.json.bak.20240602T000000"},
+			want:  "ver.json.bak.20240602T000000",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range test.files {
+				if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := LatestBackup(filepath.Join(dir, "ver.json"))
+			if test.want == "" {
+				if !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("error is %v, want one wrapping os.ErrNotExist", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(dir, test.want); got != want {
+				t.Errorf("latest backup is %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestSaveWithBackupCopiesPrevious(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ver.json")
+	if err := SaveWithBackup(path, testVersion(t, "1.0.0")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".bak"); !os.IsNotExist(err) {
+		t.Errorf("backup written for a file that didn't exist: %v", err)
+	}
+	if err := SaveWithBackup(path, testVersion(t, "1.1.0")); err != nil {
+		t.Fatal(err)
+	}
+	if got := readVersion(t, path+".bak").Version.String(); got != "1.0.0" {
+		t.Errorf("backup holds %s, want 1.0.0", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		contents string
+		is       error
+		message  string
+	}{
+		{name: "missing", file: "ver.json", is: os.ErrNotExist},
+		{name: "not json", file: "ver.json", contents: "{", message: "unable to parse"},
+		{name: "bad version", file: "ver.json", contents: `{"name": "test", "version": "one", "build": 0}`, message: "unable to parse"},
+		{name: "wrong type", file: "ver.json", contents: `{"name": "test", "version": "1.0.0", "build": "two"}`, message: "unable to parse"},
+		{name: "newer schema", file: "ver.json", contents: `{"schemaVersion": 99, "name": "test", "version": "1.0.0", "build": 0}`, is: ErrNewerSchema},
+		{name: "bad yaml", file: "ver.yaml", contents: "name: [", message: "unable to parse"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), test.file)
+			if test.contents != "" {
+				if err := os.WriteFile(path, []byte(test.contents), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			v, err := Load(path)
+			if err == nil {
+				t.Fatalf("Load succeeded with %+v", v)
+			}
+			if test.is != nil && !errors.Is(err, test.is) {
+				t.Errorf("error is %v, want one wrapping %v", err, test.is)
+			}
+			if test.message != "" && !strings.Contains(err.Error(), test.message) {
+				t.Errorf("error is %q, want it to contain %q", err, test.message)
+			}
+		})
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	created := time.Date(2024, 6, 1, 12, 3, 1, 0, time.UTC)
+	v := testVersion(t, "1.2.3-rc.1+sha.abc")
+	v.Build = 42
+	v.Author = "Jane <jane@example.com>"
+	v.Extras = map[string]string{"team": "core"}
+	v.CreatedAt = &created
+
+	for _, format := range []Format{JSON, YAML, TOML} {
+		t.Run(format.Name, func(t *testing.T) {
+			data, err := Encode(v, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Decode(data, format)
+			if err != nil {
+				t.Fatalf("Decode: %s\n%s", err, data)
+			}
+			switch {
+			case got.Version.String() != v.Version.String():
+				t.Errorf("version is %s, want %s", got.Version, v.Version)
+			case got.ProjectName != v.ProjectName || got.VersionString != v.VersionString || got.Build != v.Build:
+				t.Errorf("decoded %+v, want %+v", got, v)
+			case got.Author != v.Author:
+				t.Errorf("author is %q, want %q", got.Author, v.Author)
+			case got.Extras["team"] != "core":
+				t.Errorf("extras are %v, want team=core", got.Extras)
+			case got.CreatedAt == nil || !got.CreatedAt.Equal(created):
+				t.Errorf("createdAt is %v, want %s", got.CreatedAt, created)
+			}
+		})
+	}
+
+	// Text keeps nothing but the version
+	data, err := Encode(v, Text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(data, Text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version.String() != v.Version.String() || got.Build != 0 {
+		t.Errorf("text decoded to %s build %d, want %s build 0", got.Version, got.Build, v.Version)
+	}
+}
+
+func TestEncodeKeepsHTML(t *testing.T) {
+	v := testVersion(t, "1.0.0")
+	v.Author = "Jane <jane@example.com>"
+	data, err := Encode(v, JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"Jane <jane@example.com>"`) {
+		t.Errorf("author was escaped:\n%s", data)
+	}
+}
+
+func TestSaveChoosesFormat(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"ver.json", "ver.yaml", "ver.toml", TextFileName} {
+		path := filepath.Join(dir, name)
+		if err := Save(path, testVersion(t, "2.0.0")); err != nil {
+			t.Fatalf("Save(%s): %s", name, err)
+		}
+		if got := readVersion(t, path).Version.String(); got != "2.0.0" {
+			t.Errorf("%s holds %s, want 2.0.0", name, got)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package version
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Windows refuses to rename over a file another process has open, which
+// replaceFile waits out
+func TestReplaceFileRetriesWhileOpen(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "new")
+	dst := filepath.Join(dir, "ver.json")
+	if err := os.WriteFile(src, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	open, err := os.Open(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := make(chan struct{})
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		open.Close()
+		close(closed)
+	}()
+
+	err = replaceFile(src, dst)
+	<-closed
+	if err != nil {
+		t.Fatalf("replaceFile: %s", err)
+	}
+	contents, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "new" {
+		t.Errorf("%s holds %q, want \"new\"", dst, contents)
+	}
+}
@@ -0,0 +1,210 @@
+package version
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a storage format for the version file. Everything is marshaled
+// as JSON first and converted, so the json struct tags on GoVersion are the
+// only definition of the field names.
+type Format struct {
+	Name       string
+	Extensions []string
+	// fileName is the only file name used by a format without extensions
+	fileName string
+	toJSON   func([]byte) ([]byte, error)
+	fromJSON func([]byte) ([]byte, error)
+}
+
+var JSON = Format{
+	Name:       "json",
+	Extensions: []string{".json"},
+	toJSON:     func(b []byte) ([]byte, error) { return b, nil },
+	fromJSON:   func(b []byte) ([]byte, error) { return b, nil },
+}
+
+var YAML = Format{
+	Name:       "yaml",
+	Extensions: []string{".yaml", ".yml"},
+	toJSON:     yamlToJSON,
+	fromJSON:   jsonToYAML,
+}
+
+var TOML = Format{
+	Name:       "toml",
+	Extensions: []string{".toml"},
+	toJSON:     tomlToJSON,
+	fromJSON:   jsonToTOML,
+}
+
+// TextFileName is the name of a Text version file
+const TextFileName string = "VERSION"
+
+// Text stores nothing but the version on a single line. The other fields are
+// dropped when saving and empty when loading.
+var Text = Format{
+	Name:     "text",
+	fileName: TextFileName,
+	toJSON:   textToJSON,
+	fromJSON: jsonToText,
+}
+
+// Formats lists every supported storage format
+var Formats = []Format{JSON, YAML, TOML, Text}
+
+// FileNames are the default version file names, used when searching for a
+// version file
+var FileNames = []string{"ver.json", "ver.yaml", "ver.yml", "ver.toml"}
+
+// ToJSON converts data in this format to JSON
+func (f Format) ToJSON(data []byte) ([]byte, error) {
+	return f.toJSON(data)
+}
+
+// FromJSON converts JSON to this format
+func (f Format) FromJSON(data []byte) ([]byte, error) {
+	return f.fromJSON(data)
+}
+
+// FileName is the default version file name for the format, e.g. ver.yaml
+func (f Format) FileName() string {
+	if f.fileName != "" {
+		return f.fileName
+	}
+	return "ver" + f.Extensions[0]
+}
+
+// FormatFor selects the format by file extension, or Text for a file named
+// TextFileName. Anything unrecognized is JSON.
+func FormatFor(path string) Format {
+	if filepath.Base(path) == TextFileName {
+		return Text
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, format := range Formats {
+		for _, e := range format.Extensions {
+			if e == ext {
+				return format
+			}
+		}
+	}
+	return JSON
+}
+
+// FormatNamed looks up a format by name, e.g. "yaml"
+func FormatNamed(name string) (Format, bool) {
+	for _, format := range Formats {
+		if format.Name == name {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+// FormatNames lists the names of every supported format
+func FormatNames() []string {
+	var names []string
+	for _, format := range Formats {
+		names = append(names, format.Name)
+	}
+	return names
+}
+
+func textToJSON(b []byte) ([]byte, error) {
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return json.Marshal(map[string]string{"version": line})
+		}
+	}
+	return nil, errors.New("file is empty")
+}
+
+func jsonToText(b []byte) ([]byte, error) {
+	var value struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return nil, err
+	}
+	return []byte(value.Version + "\n"), nil
+}
+
+func yamlToJSON(b []byte) ([]byte, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(b, &value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// JSON is valid YAML, so decoding it into a yaml.Node keeps the field order
+// of the struct. Clearing the styles turns the JSON flow syntax into ordinary
+// block YAML.
+func jsonToYAML(b []byte) ([]byte, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return nil, err
+	}
+	clearStyle(&node)
+	return yaml.Marshal(&node)
+}
+
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+func tomlToJSON(b []byte) ([]byte, error) {
+	var value map[string]interface{}
+	if err := toml.Unmarshal(b, &value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+func jsonToTOML(b []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	var value map[string]interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := toml.NewEncoder(&out).Encode(convertNumbers(value)); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Converts json.Number values into int64 or float64 so that TOML doesn't
+// write integers like the build number as floats
+func convertNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = convertNumbers(child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = convertNumbers(child)
+		}
+	}
+	return value
+}
@@ -0,0 +1,112 @@
+package version
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"strconv"
+	"text/template"
+)
+
+var ErrIdentifierNotFound = errors.New("identifier not found")
+
+// Sets the GoVar const or var in the Go file at path, leaving the file alone
+// when it already has the value. Only the string literal is replaced, and
+// the result is gofmt'ed.
+func (t SyncTarget) syncGoVar(path string, v *GoVersion, prefix string) (bool, error) {
+	if t.Pattern != "" || t.Template != "" {
+		return false, fmt.Errorf("%w: %s needs exactly one of pattern, template or goVar", ErrInvalidTarget, t.File)
+	}
+	value, err := t.goVarValue(v, prefix)
+	if err != nil {
+		return false, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, contents, parser.ParseComments)
+	if err != nil {
+		return false, err
+	}
+
+	lit, err := findStringLiteral(file, t.GoVar)
+	if err != nil {
+		return false, fmt.Errorf("%w in %s", err, t.File)
+	}
+	current, err := strconv.Unquote(lit.Value)
+	if err == nil && current == value {
+		return false, nil
+	}
+
+	start := fset.Position(lit.Pos()).Offset
+	end := fset.Position(lit.End()).Offset
+	var updated bytes.Buffer
+	updated.Write(contents[:start])
+	updated.WriteString(strconv.Quote(value))
+	updated.Write(contents[end:])
+	formatted, err := format.Source(updated.Bytes())
+	if err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(path, formatted, info.Mode().Perm()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// The value the variable is set to, Value executed against v or else the
+// version, with prefix in front of it either way
+func (t SyncTarget) goVarValue(v *GoVersion, prefix string) (string, error) {
+	data := NewTemplateData(v, prefix)
+	if t.Value == "" {
+		return data.Version.String(), nil
+	}
+	tmpl, err := template.New(t.File).Option("missingkey=error").Parse(t.Value)
+	if err != nil {
+		return "", fmt.Errorf("%w: value for %s: %s", ErrInvalidTarget, t.File, err)
+	}
+	var value bytes.Buffer
+	if err := tmpl.Execute(&value, data); err != nil {
+		return "", fmt.Errorf("%w: value for %s: %s", ErrInvalidTarget, t.File, err)
+	}
+	return value.String(), nil
+}
+
+// The string literal a package-level const or var is declared with
+func findStringLiteral(file *ast.File, name string) (*ast.BasicLit, error) {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || (gen.Tok != token.CONST && gen.Tok != token.VAR) {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			valueSpec := spec.(*ast.ValueSpec)
+			for i, ident := range valueSpec.Names {
+				if ident.Name != name {
+					continue
+				}
+				if i >= len(valueSpec.Values) {
+					return nil, fmt.Errorf("%s has no value to replace", name)
+				}
+				lit, ok := valueSpec.Values[i].(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					return nil, fmt.Errorf("%s isn't set to a string literal", name)
+				}
+				return lit, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrIdentifierNotFound, name)
+}
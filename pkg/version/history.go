@@ -0,0 +1,58 @@
+package version
+
+import (
+	"time"
+
+	"github.com/Masterminds/semver"
+)
+
+// DefaultHistoryLimit is the number of history entries kept when the version
+// file doesn't set historyLimit
+const DefaultHistoryLimit int = 100
+
+// HistoryEntry is a single version change
+type HistoryEntry struct {
+	Previous  *semver.Version `json:"previous" jsonschema:"nullable"`
+	Version   *semver.Version `json:"version"`
+	Build     int             `json:"build" jsonschema:"minimum=0"`
+	Timestamp time.Time       `json:"timestamp"`
+	// VersionString is the codename of Version, missing from entries
+	// written before schema 4
+	VersionString string `json:"versionString,omitempty"`
+	// PreviousBuild and PreviousVersionString are the build and codename
+	// before the change, so that undoing it restores them as well. Both are
+	// missing from entries written before schema 7.
+	PreviousBuild         *int   `json:"previousBuild,omitempty" jsonschema:"minimum=0"`
+	PreviousVersionString string `json:"previousVersionString,omitempty"`
+}
+
+// RecordHistory appends the change from before to the current version,
+// dropping the oldest entries beyond the limit. A negative HistoryLimit turns
+// history off.
+func (v *GoVersion) RecordHistory(before *GoVersion) {
+	limit := v.HistoryLimit
+	if limit == 0 {
+		limit = DefaultHistoryLimit
+	}
+	if limit < 0 {
+		return
+	}
+
+	entry := HistoryEntry{
+		Version:       v.Version,
+		Build:         v.Build,
+		Timestamp:     time.Now().UTC().Truncate(time.Second),
+		VersionString: v.VersionString,
+	}
+	if before != nil {
+		build := before.Build
+		entry.Previous = before.Version
+		entry.PreviousBuild = &build
+		entry.PreviousVersionString = before.VersionString
+	}
+	v.Undone = nil
+	v.History = append(v.History, entry)
+	if len(v.History) > limit {
+		v.History = v.History[len(v.History)-limit:]
+	}
+}
@@ -0,0 +1,298 @@
+package version
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+)
+
+// JSONSchemaDialect is the JSON Schema draft JSONSchema follows
+const JSONSchemaDialect string = "https://json-schema.org/draft/2020-12/schema"
+
+// SemverPattern matches the versions gover writes, from semver.org with the
+// "v" older files were written with allowed
+const SemverPattern string = `^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`
+
+// Schema is the part of JSON Schema needed to describe a version file
+type Schema struct {
+	Dialect string `json:"$schema,omitempty"`
+	Title   string `json:"title,omitempty"`
+	// Types are written as a single type unless the value can also be null
+	Types                []string           `json:"-"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Maximum              *int               `json:"maximum,omitempty"`
+}
+
+// MarshalJSON writes Types as the "type" keyword
+func (s Schema) MarshalJSON() ([]byte, error) {
+	type schema Schema
+	var types interface{}
+	switch len(s.Types) {
+	case 0:
+	case 1:
+		types = s.Types[0]
+	default:
+		types = s.Types
+	}
+	// The outer Dialect and Title hide the embedded ones, keeping them first
+	return json.Marshal(struct {
+		Dialect string      `json:"$schema,omitempty"`
+		Title   string      `json:"title,omitempty"`
+		Type    interface{} `json:"type,omitempty"`
+		schema
+	}{s.Dialect, s.Title, types, schema(s)})
+}
+
+var (
+	semverType = reflect.TypeOf(semver.Version{})
+	timeType   = reflect.TypeOf(time.Time{})
+)
+
+// JSONSchema describes the version file, generated from GoVersion so that
+// it follows the struct. Fields without omitempty are required, and fields
+// gover doesn't know about aren't allowed because saving would drop them.
+func JSONSchema() *Schema {
+	s := schemaFor(reflect.TypeOf(GoVersion{}))
+	s.Dialect = JSONSchemaDialect
+	s.Title = "gover version file"
+	maximum := CurrentSchema
+	s.Properties["schemaVersion"].Maximum = &maximum
+	return s
+}
+
+func schemaFor(t reflect.Type) *Schema {
+	switch t {
+	case semverType:
+		return &Schema{Types: []string{"string"}, Pattern: SemverPattern}
+	case timeType:
+		return &Schema{Types: []string{"string"}, Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaFor(t.Elem())
+	case reflect.Slice:
+		return &Schema{Types: []string{"array"}, Items: schemaFor(t.Elem())}
+	case reflect.Map:
+		// Keys are free-form, so there are no properties to check
+		return &Schema{Types: []string{"object"}}
+	case reflect.Struct:
+		closed := false
+		s := &Schema{
+			Types:                []string{"object"},
+			Properties:           make(map[string]*Schema),
+			AdditionalProperties: &closed,
+		}
+		addProperties(s, t)
+		sort.Strings(s.Required)
+		return s
+	case reflect.Bool:
+		return &Schema{Types: []string{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Types: []string{"integer"}}
+	}
+	return &Schema{Types: []string{"string"}}
+}
+
+// Adds the fields of struct t to s, including those of embedded structs the
+// way encoding/json flattens them. A jsonschema tag can add a minimum, e.g.
+// `jsonschema:"minimum=0"`, and allow null with `jsonschema:"nullable"`.
+func addProperties(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if field.Anonymous && tag == "" {
+			addProperties(s, field.Type)
+			continue
+		}
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+
+		options := strings.Split(tag, ",")
+		name := options[0]
+		if name == "" {
+			name = field.Name
+		}
+		property := schemaFor(field.Type)
+		for _, option := range strings.Split(field.Tag.Get("jsonschema"), ",") {
+			key, value, _ := strings.Cut(option, "=")
+			switch key {
+			case "minimum":
+				minimum, _ := strconv.Atoi(value)
+				property.Minimum = &minimum
+			case "nullable":
+				property.Types = append(property.Types, "null")
+			}
+		}
+		s.Properties[name] = property
+
+		omitempty := false
+		for _, option := range options[1:] {
+			omitempty = omitempty || option == "omitempty"
+		}
+		if !omitempty {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// Validate checks JSON data against s, returning each place it doesn't
+// match as "path: problem", or just the problem for the top level
+func (s *Schema) Validate(data []byte) []string {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return []string{fmt.Sprintf("not valid JSON: %s", err)}
+	}
+	return s.validate("", value)
+}
+
+func (s *Schema) validate(path string, value interface{}) []string {
+	at := func(format string, a ...interface{}) []string {
+		if path == "" {
+			return []string{fmt.Sprintf(format, a...)}
+		}
+		return []string{path + ": " + fmt.Sprintf(format, a...)}
+	}
+
+	kind := jsonKind(value)
+	allowed := false
+	for _, t := range s.Types {
+		allowed = allowed || t == kind || (t == "number" && kind == "integer")
+	}
+	if len(s.Types) > 0 && !allowed {
+		return at("is %s, should be %s", kind, strings.Join(s.Types, " or "))
+	}
+
+	var problems []string
+	switch value := value.(type) {
+	case string:
+		if s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(value) {
+			problems = append(problems, at("'%s' doesn't match %s", value, s.Pattern)...)
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				problems = append(problems, at("'%s' is not an RFC 3339 date-time", value)...)
+			}
+		}
+	case json.Number:
+		n, _ := value.Int64()
+		if s.Minimum != nil && n < int64(*s.Minimum) {
+			problems = append(problems, at("%s is less than the minimum %d", value, *s.Minimum)...)
+		}
+		if s.Maximum != nil && n > int64(*s.Maximum) {
+			problems = append(problems, at("%s is more than the maximum %d", value, *s.Maximum)...)
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range value {
+				problems = append(problems, s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item)...)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				problems = append(problems, at("%s is required", name)...)
+			}
+		}
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := s.Properties[name]
+			if !ok && s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				problems = append(problems, at("%s is not a known field", name)...)
+				continue
+			}
+			if ok {
+				child := name
+				if path != "" {
+					child = path + "." + name
+				}
+				problems = append(problems, property.validate(child, value[name])...)
+			}
+		}
+	}
+	return problems
+}
+
+// Name of the JSON Schema type of a decoded value
+func jsonKind(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+// UnknownFields returns the path of each key in JSON data that s has no
+// property for, such as history[0].versoin, leaving out free-form objects
+func (s *Schema) UnknownFields(data []byte) ([]string, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return s.unknownFields("", value), nil
+}
+
+func (s *Schema) unknownFields(path string, value interface{}) []string {
+	var unknown []string
+	switch value := value.(type) {
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range value {
+				unknown = append(unknown, s.Items.unknownFields(fmt.Sprintf("%s[%d]", path, i), item)...)
+			}
+		}
+	case map[string]interface{}:
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			child := name
+			if path != "" {
+				child = path + "." + name
+			}
+			property, ok := s.Properties[name]
+			if !ok && s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				unknown = append(unknown, child)
+			} else if ok {
+				unknown = append(unknown, property.unknownFields(child, value[name])...)
+			}
+		}
+	}
+	return unknown
+}
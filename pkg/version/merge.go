@@ -0,0 +1,205 @@
+package version
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Fields Merge resolves itself, every other field takes whichever side
+// changed it
+var mergedFields = map[string]bool{
+	"version":       true,
+	"versionString": true,
+	"commit":        true,
+	"build":         true,
+	"history":       true,
+	"createdAt":     true,
+	"updatedAt":     true,
+	"checksum":      true,
+}
+
+// Merge resolves a three-way merge of a version file: the higher version
+// wins, along with its codename and commit, the build is the higher of the
+// two, and the histories are combined. Any other field changed differently
+// on both sides can't be resolved, Merge returns those fields as conflicts
+// and a nil version. ancestor may be empty when the file was added on both
+// sides.
+func Merge(ancestor, ours, theirs *GoVersion) (*GoVersion, []string, error) {
+	if ours.Version == nil || theirs.Version == nil {
+		return nil, nil, ErrNoVersion
+	}
+
+	base, err := fieldsOf(ancestor)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := fieldsOf(ours)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := fieldsOf(theirs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var conflicts []string
+	merged := make(map[string]json.RawMessage)
+	for _, name := range unionKeys(a, b) {
+		if mergedFields[name] {
+			continue
+		}
+		value, ok := mergeField(base[name], a[name], b[name])
+		if !ok {
+			conflicts = append(conflicts, name)
+			continue
+		}
+		if value != nil {
+			merged[name] = value
+		}
+	}
+
+	// Versions of the same precedence that differ in their metadata have no
+	// higher one to pick
+	winner := ours
+	switch ours.Version.Compare(theirs.Version) {
+	case -1:
+		winner = theirs
+	case 0:
+		if ours.Version.String() != theirs.Version.String() {
+			conflicts = append(conflicts, "version")
+		} else if ours.VersionString != theirs.VersionString {
+			value, ok := mergeField(base["versionString"], a["versionString"], b["versionString"])
+			if !ok {
+				conflicts = append(conflicts, "versionString")
+			} else if string(value) == string(b["versionString"]) {
+				winner = theirs
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return nil, conflicts, nil
+	}
+
+	encoded, err := json.Marshal(merged)
+	if err != nil {
+		return nil, nil, err
+	}
+	var result GoVersion
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		return nil, nil, err
+	}
+
+	result.Version = winner.Version
+	result.VersionString = winner.VersionString
+	result.Commit = winner.Commit
+	result.Build = ours.Build
+	if theirs.Build > result.Build {
+		result.Build = theirs.Build
+	}
+	result.History = mergeHistory(ours.History, theirs.History, result.HistoryLimit)
+	result.CreatedAt = earliest(ours, theirs)
+	result.UpdatedAt = ours.UpdatedAt
+	if theirs.UpdatedAt != nil && (ours.UpdatedAt == nil || theirs.UpdatedAt.After(*ours.UpdatedAt)) {
+		result.UpdatedAt = theirs.UpdatedAt
+	}
+	if ours.Checksum != "" || theirs.Checksum != "" {
+		if err := result.Seal(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return &result, nil, nil
+}
+
+func fieldsOf(v *GoVersion) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if v == nil {
+		return fields, nil
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal version object: %w", err)
+	}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func unionKeys(a, b map[string]json.RawMessage) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, fields := range []map[string]json.RawMessage{a, b} {
+		for key := range fields {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Three-way merge of a single value, nil meaning the field is missing
+func mergeField(base, a, b json.RawMessage) (json.RawMessage, bool) {
+	switch {
+	case bytes.Equal(a, b):
+		return a, true
+	case bytes.Equal(a, base):
+		return b, true
+	case bytes.Equal(b, base):
+		return a, true
+	}
+	return nil, false
+}
+
+// Both histories in order, an entry recorded on both sides kept once, and
+// trimmed to limit the way RecordHistory would
+func mergeHistory(a, b []HistoryEntry, limit int) []HistoryEntry {
+	type key struct {
+		previous, version string
+		build             int
+		timestamp         int64
+	}
+	keyOf := func(entry HistoryEntry) key {
+		k := key{build: entry.Build, timestamp: entry.Timestamp.Unix()}
+		if entry.Previous != nil {
+			k.previous = entry.Previous.String()
+		}
+		if entry.Version != nil {
+			k.version = entry.Version.String()
+		}
+		return k
+	}
+
+	seen := make(map[key]bool)
+	var merged []HistoryEntry
+	for _, entry := range append(append([]HistoryEntry{}, a...), b...) {
+		if k := keyOf(entry); !seen[k] {
+			seen[k] = true
+			merged = append(merged, entry)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Timestamp.Before(merged[j].Timestamp)
+	})
+
+	if limit == 0 {
+		limit = DefaultHistoryLimit
+	}
+	if limit > 0 && len(merged) > limit {
+		merged = merged[len(merged)-limit:]
+	}
+	return merged
+}
+
+func earliest(a, b *GoVersion) *time.Time {
+	if a.CreatedAt == nil || (b.CreatedAt != nil && b.CreatedAt.Before(*a.CreatedAt)) {
+		return b.CreatedAt
+	}
+	return a.CreatedAt
+}
@@ -0,0 +1,248 @@
+package version
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/semver"
+)
+
+func TestMerge(t *testing.T) {
+	at := func(minutes int) time.Time {
+		return time.Date(2024, 6, 1, 12, minutes, 0, 0, time.UTC)
+	}
+	entry := func(previous, version string, build, minutes int) HistoryEntry {
+		return HistoryEntry{
+			Previous:  semver.MustParse(previous),
+			Version:   semver.MustParse(version),
+			Build:     build,
+			Timestamp: at(minutes),
+		}
+	}
+	file := func(version, codename, commit string, build int, change func(v *GoVersion)) *GoVersion {
+		v := &GoVersion{
+			ProjectName:   "test",
+			Version:       semver.MustParse(version),
+			VersionString: codename,
+			Commit:        commit,
+			Build:         build,
+			History:       []HistoryEntry{entry("1.1.0", "1.2.0", 3, 0)},
+		}
+		if change != nil {
+			change(v)
+		}
+		return v
+	}
+	ancestor := file("1.2.0", "apple", "a1", 3, nil)
+
+	tests := []struct {
+		name      string
+		ancestor  *GoVersion
+		ours      *GoVersion
+		theirs    *GoVersion
+		version   string
+		codename  string
+		commit    string
+		build     int
+		history   []string
+		conflicts []string
+	}{
+		{
+			name:     "ours ahead",
+			ancestor: ancestor,
+			ours:     file("1.3.0", "banana", "b2", 4, nil),
+			theirs:   file("1.2.1", "cherry", "c2", 4, nil),
+			version:  "1.3.0",
+			codename: "banana",
+			commit:   "b2",
+			build:    4,
+			history:  []string{"1.2.0"},
+		},
+		{
+			name:     "theirs ahead",
+			ancestor: ancestor,
+			ours:     file("1.2.1", "cherry", "c2", 4, nil),
+			theirs:   file("2.0.0", "durian", "d2", 4, nil),
+			version:  "2.0.0",
+			codename: "durian",
+			commit:   "d2",
+			build:    4,
+			history:  []string{"1.2.0"},
+		},
+		{
+			name:     "builds diverge",
+			ancestor: ancestor,
+			ours:     file("1.2.0", "apple", "a1", 7, nil),
+			theirs:   file("1.2.0", "apple", "a1", 9, nil),
+			version:  "1.2.0",
+			codename: "apple",
+			commit:   "a1",
+			build:    9,
+			history:  []string{"1.2.0"},
+		},
+		{
+			name:     "one side changed a field",
+			ancestor: ancestor,
+			ours:     file("1.2.0", "apple", "a1", 3, func(v *GoVersion) { v.Author = "Jane" }),
+			theirs:   file("1.2.0", "apple", "a1", 3, nil),
+			version:  "1.2.0",
+			codename: "apple",
+			commit:   "a1",
+			build:    3,
+			history:  []string{"1.2.0"},
+		},
+		{
+			name:      "both changed a field",
+			ancestor:  ancestor,
+			ours:      file("1.3.0", "banana", "b2", 4, func(v *GoVersion) { v.Author = "Jane" }),
+			theirs:    file("1.2.1", "cherry", "c2", 4, func(v *GoVersion) { v.Author = "John" }),
+			conflicts: []string{"author"},
+		},
+		{
+			name:     "both changed a field the same way",
+			ancestor: ancestor,
+			ours:     file("1.2.0", "apple", "a1", 3, func(v *GoVersion) { v.Description = "api" }),
+			theirs:   file("1.2.0", "apple", "a1", 3, func(v *GoVersion) { v.Description = "api" }),
+			version:  "1.2.0",
+			codename: "apple",
+			commit:   "a1",
+			build:    3,
+			history:  []string{"1.2.0"},
+		},
+		{
+			name:     "histories combined",
+			ancestor: ancestor,
+			ours: file("1.3.0", "banana", "b2", 4, func(v *GoVersion) {
+				v.History = append(v.History, entry("1.2.0", "1.3.0", 4, 20))
+			}),
+			theirs: file("1.2.1", "cherry", "c2", 4, func(v *GoVersion) {
+				v.History = append(v.History, entry("1.2.0", "1.2.1", 4, 10))
+			}),
+			version:  "1.3.0",
+			codename: "banana",
+			commit:   "b2",
+			build:    4,
+			history:  []string{"1.2.0", "1.2.1", "1.3.0"},
+		},
+		{
+			name:     "identical histories deduplicated",
+			ancestor: ancestor,
+			ours: file("1.3.0", "banana", "b2", 4, func(v *GoVersion) {
+				v.History = append(v.History, entry("1.2.0", "1.3.0", 4, 20))
+			}),
+			theirs: file("1.3.0", "banana", "b2", 4, func(v *GoVersion) {
+				v.History = append(v.History, entry("1.2.0", "1.3.0", 4, 20))
+			}),
+			version:  "1.3.0",
+			codename: "banana",
+			commit:   "b2",
+			build:    4,
+			history:  []string{"1.2.0", "1.3.0"},
+		},
+		{
+			name:     "added on both sides",
+			ours:     file("1.0.0", "apple", "", 1, nil),
+			theirs:   file("1.1.0", "banana", "", 2, nil),
+			version:  "1.1.0",
+			codename: "banana",
+			build:    2,
+			history:  []string{"1.2.0"},
+		},
+		{
+			// Only the metadata differs, neither version is higher
+			name:      "same precedence",
+			ancestor:  ancestor,
+			ours:      file("1.3.0+linux", "banana", "b2", 4, nil),
+			theirs:    file("1.3.0+darwin", "banana", "b2", 4, nil),
+			conflicts: []string{"version"},
+		},
+		{
+			name:     "same version, codename changed on one side",
+			ancestor: file("1.3.0", "banana", "b2", 4, nil),
+			ours:     file("1.3.0", "banana", "b2", 4, nil),
+			theirs:   file("1.3.0", "blueberry", "b2", 4, nil),
+			version:  "1.3.0",
+			codename: "blueberry",
+			commit:   "b2",
+			build:    4,
+			history:  []string{"1.2.0"},
+		},
+		{
+			name:      "same version, codename changed on both sides",
+			ancestor:  file("1.3.0", "banana", "b2", 4, nil),
+			ours:      file("1.3.0", "bilberry", "b2", 4, nil),
+			theirs:    file("1.3.0", "blueberry", "b2", 4, nil),
+			conflicts: []string{"versionString"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged, conflicts, err := Merge(test.ancestor, test.ours, test.theirs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.conflicts != nil {
+				if !reflect.DeepEqual(conflicts, test.conflicts) {
+					t.Errorf("conflicts are %v, want %v", conflicts, test.conflicts)
+				}
+				if merged != nil {
+					t.Errorf("merged to %+v despite conflicts", merged)
+				}
+				return
+			}
+			if len(conflicts) > 0 {
+				t.Fatalf("unexpected conflicts: %v", conflicts)
+			}
+
+			switch {
+			case merged.Version.String() != test.version:
+				t.Errorf("version is %s, want %s", merged.Version, test.version)
+			case merged.VersionString != test.codename:
+				t.Errorf("codename is '%s', want '%s'", merged.VersionString, test.codename)
+			case merged.Commit != test.commit:
+				t.Errorf("commit is '%s', want '%s'", merged.Commit, test.commit)
+			case merged.Build != test.build:
+				t.Errorf("build is %d, want %d", merged.Build, test.build)
+			}
+			var history []string
+			for _, entry := range merged.History {
+				history = append(history, entry.Version.String())
+			}
+			if !reflect.DeepEqual(history, test.history) {
+				t.Errorf("history is %v, want %v", history, test.history)
+			}
+			if test.ours.Author != "" && merged.Author != test.ours.Author {
+				t.Errorf("author is '%s', want '%s'", merged.Author, test.ours.Author)
+			}
+			if test.ours.Description != "" && merged.Description != test.ours.Description {
+				t.Errorf("description is '%s', want '%s'", merged.Description, test.ours.Description)
+			}
+		})
+	}
+}
+
+func TestMergeNoVersion(t *testing.T) {
+	ours := &GoVersion{ProjectName: "test"}
+	theirs := &GoVersion{ProjectName: "test", Version: semver.MustParse("1.0.0")}
+	if _, _, err := Merge(nil, ours, theirs); !errors.Is(err, ErrNoVersion) {
+		t.Errorf("error is %v, want %v", err, ErrNoVersion)
+	}
+}
+
+func TestMergeHistoryLimit(t *testing.T) {
+	var a, b []HistoryEntry
+	for i := 0; i < 4; i++ {
+		entry := HistoryEntry{Version: semver.MustParse("1.0.0"), Build: i, Timestamp: time.Unix(int64(i), 0)}
+		if i%2 == 0 {
+			a = append(a, entry)
+		} else {
+			b = append(b, entry)
+		}
+	}
+	merged := mergeHistory(a, b, 3)
+	if len(merged) != 3 || merged[0].Build != 1 || merged[2].Build != 3 {
+		t.Errorf("merged history is %+v, want builds 1, 2 and 3", merged)
+	}
+}
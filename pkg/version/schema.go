@@ -0,0 +1,117 @@
+package version
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CurrentSchema is the schemaVersion this version of gover reads and writes.
+// Files from before schemaVersion existed are schema 1.
+//
+//	1: name, version, versionString and build
+//	2: schemaVersion, createdAt and updatedAt, version written without a "v"
+//	3: calver
+//	4: versionString in history entries
+//	5: author and description
+//	6: extras
+//	7: previousBuild and previousVersionString in history entries
+const CurrentSchema int = 7
+
+var ErrNewerSchema = errors.New("version file schema is newer than this gover")
+
+// Each migration upgrades a file by one schema, migrations[0] from 1 to 2,
+// and describes what it changed
+var migrations = []func(v *GoVersion) []string{
+	migrateToSchema2,
+	migrateToSchema3,
+	migrateToSchema4,
+	migrateToSchema5,
+	migrateToSchema6,
+	migrateToSchema7,
+}
+
+func checkSchema(v *GoVersion) error {
+	if v.SchemaVersion > CurrentSchema {
+		return fmt.Errorf("%w: schemaVersion is %d, this gover understands up to %d", ErrNewerSchema, v.SchemaVersion, CurrentSchema)
+	}
+	return nil
+}
+
+// UnknownFields returns the keys in data that CurrentSchema doesn't define,
+// which Decode drops and the next save loses. Every schema so far has only
+// added fields, so these are typos or fields of a newer gover.
+func UnknownFields(data []byte, format Format) ([]string, error) {
+	jsonBytes, err := format.ToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return JSONSchema().UnknownFields(jsonBytes)
+}
+
+// Migrate upgrades v to CurrentSchema in place, returning a description of
+// each change. Nothing changes for a file already at CurrentSchema.
+func (v *GoVersion) Migrate() ([]string, error) {
+	if err := checkSchema(v); err != nil {
+		return nil, err
+	}
+	from := v.SchemaVersion
+	if from == 0 {
+		from = 1
+	}
+
+	var changes []string
+	for schema := from; schema < CurrentSchema; schema++ {
+		changes = append(changes, migrations[schema-1](v)...)
+	}
+	if v.SchemaVersion != CurrentSchema {
+		changes = append(changes, fmt.Sprintf("schemaVersion %d -> %d", from, CurrentSchema))
+		v.SchemaVersion = CurrentSchema
+	}
+	return changes, nil
+}
+
+func migrateToSchema2(v *GoVersion) []string {
+	var changes []string
+	if v.Version != nil && strings.HasPrefix(v.Version.Original(), "v") {
+		changes = append(changes, fmt.Sprintf("version %s is written as %s", v.Version.Original(), v.Version))
+	}
+	if v.CreatedAt == nil && len(v.History) > 0 {
+		created := v.History[0].Timestamp
+		v.CreatedAt = &created
+		changes = append(changes, fmt.Sprintf("createdAt set to %s from the first history entry", created.Format(time.RFC3339)))
+	} else if v.CreatedAt == nil {
+		changes = append(changes, "createdAt set to the time the file is written")
+	}
+	return changes
+}
+
+// CalVer is new and optional, schema 3 only exists so that older gover
+// refuses files using it
+func migrateToSchema3(v *GoVersion) []string {
+	return nil
+}
+
+// Codenames of past versions weren't recorded, and can't be recovered
+func migrateToSchema4(v *GoVersion) []string {
+	return nil
+}
+
+// Like calver, author and description are optional, older gover would
+// drop them
+func migrateToSchema5(v *GoVersion) []string {
+	return nil
+}
+
+// Adds extras, optional custom fields that older gover would drop. Nothing
+// in an existing file changes.
+func migrateToSchema6(v *GoVersion) []string {
+	return nil
+}
+
+// The build and codename before older changes weren't recorded, undoing
+// those only restores the version
+func migrateToSchema7(v *GoVersion) []string {
+	return nil
+}
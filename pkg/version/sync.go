@@ -0,0 +1,130 @@
+package version
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrPatternNotFound = errors.New("pattern not found")
+	ErrInvalidTarget   = errors.New("invalid sync target")
+)
+
+// TemplatePlaceholder marks where the version goes in a SyncTarget template
+const TemplatePlaceholder string = "{{.Version}}"
+
+// Matches a version in a file being synced, leading "v" excluded
+const versionPattern string = `[0-9]+\.[0-9]+\.[0-9]+(?:[-+][0-9A-Za-z.+-]*[0-9A-Za-z])?`
+
+// SyncTarget is another file that carries a copy of the version. Exactly one
+// of Pattern, Template and GoVar is set. Pattern is a regular expression whose
+// capture groups are replaced with the version. Template is literal text with
+// TemplatePlaceholder where the version appears, e.g. "ARG VERSION={{.Version}}".
+// GoVar is a package-level string const or var in a Go source file, which is
+// set to Value, a text/template executed against the TemplateData, or to the
+// version when Value is empty. Both give the version with its prefix.
+type SyncTarget struct {
+	File     string `json:"file"`
+	Pattern  string `json:"pattern,omitempty"`
+	Template string `json:"template,omitempty"`
+	GoVar    string `json:"goVar,omitempty"`
+	Value    string `json:"value,omitempty"`
+}
+
+func (t SyncTarget) compile() (*regexp.Regexp, error) {
+	if t.File == "" {
+		return nil, fmt.Errorf("%w: file is not set", ErrInvalidTarget)
+	}
+	if (t.Pattern == "") == (t.Template == "") {
+		return nil, fmt.Errorf("%w: %s needs exactly one of pattern, template or goVar", ErrInvalidTarget, t.File)
+	}
+
+	if t.Template != "" {
+		if !strings.Contains(t.Template, TemplatePlaceholder) {
+			return nil, fmt.Errorf("%w: template for %s doesn't contain %s", ErrInvalidTarget, t.File, TemplatePlaceholder)
+		}
+		literals := strings.Split(t.Template, TemplatePlaceholder)
+		for i := range literals {
+			literals[i] = regexp.QuoteMeta(literals[i])
+		}
+		return regexp.MustCompile(strings.Join(literals, "("+versionPattern+")")), nil
+	}
+
+	re, err := regexp.Compile(t.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: pattern for %s: %s", ErrInvalidTarget, t.File, err)
+	}
+	if re.NumSubexp() < 1 {
+		return nil, fmt.Errorf("%w: pattern for %s has no capture group", ErrInvalidTarget, t.File)
+	}
+	return re, nil
+}
+
+// Sync rewrites every match of the target in its file with the current
+// version, returning whether the file changed. Relative file names are
+// resolved against dir, normally the directory holding the version file.
+// prefix goes in front of the version a GoVar is set to, normally
+// Settings.ValuePrefix; patterns and templates match the bare version.
+func (t SyncTarget) Sync(dir string, v *GoVersion, prefix string) (bool, error) {
+	if v.Version == nil {
+		return false, ErrNoVersion
+	}
+	path := t.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if t.GoVar != "" {
+		return t.syncGoVar(path, v, prefix)
+	}
+	if t.Value != "" {
+		return false, fmt.Errorf("%w: value for %s is only used with goVar", ErrInvalidTarget, t.File)
+	}
+	re, err := t.compile()
+	if err != nil {
+		return false, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	matches := re.FindAllSubmatchIndex(contents, -1)
+	if len(matches) == 0 {
+		return false, ErrPatternNotFound
+	}
+
+	// Every capture group of every match is replaced. Groups nested inside
+	// one already replaced are skipped.
+	replacement := v.Version.String()
+	var updated strings.Builder
+	last := 0
+	for _, groups := range matches {
+		for g := 1; g < len(groups)/2; g++ {
+			start, end := groups[2*g], groups[2*g+1]
+			if start < last {
+				continue
+			}
+			updated.Write(contents[last:start])
+			updated.WriteString(replacement)
+			last = end
+		}
+	}
+	updated.Write(contents[last:])
+
+	if updated.String() == string(contents) {
+		return false, nil
+	}
+	if err := os.WriteFile(path, []byte(updated.String()), info.Mode().Perm()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
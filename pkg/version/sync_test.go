@@ -0,0 +1,122 @@
+package version
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const goVarSource = `package build
+
+// Version is set by gover
+const Version = "0.0.0"
+
+var (
+	Name  = "test"
+	Other = 1
+)
+`
+
+func TestSyncGoVar(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  SyncTarget
+		prefix  string
+		want    string
+		changed bool
+		fails   bool
+		err     error
+	}{
+		{name: "version", target: SyncTarget{GoVar: "Version"}, want: `const Version = "1.2.3"`, changed: true},
+		{name: "prefixed version", target: SyncTarget{GoVar: "Version"}, prefix: "v", want: `const Version = "v1.2.3"`, changed: true},
+		{name: "value", target: SyncTarget{GoVar: "Version", Value: "{{.Version}}+{{.Build}}"}, want: `const Version = "1.2.3+7"`, changed: true},
+		{name: "prefixed value", target: SyncTarget{GoVar: "Version", Value: "{{.Version}}+{{.Build}}"}, prefix: "v", want: `const Version = "v1.2.3+7"`, changed: true},
+		{name: "semver methods", target: SyncTarget{GoVar: "Version", Value: "{{.Version.Major}}.{{.Version.Minor}}"}, prefix: "v", want: `const Version = "1.2"`, changed: true},
+		{name: "unchanged", target: SyncTarget{GoVar: "Name", Value: "{{.ProjectName}}"}, want: `Name  = "test"`},
+		{name: "var in a group", target: SyncTarget{GoVar: "Name", Value: "{{.VersionString}}"}, want: `Name  = "codename"`, changed: true},
+		{name: "missing", target: SyncTarget{GoVar: "Missing"}, fails: true, err: ErrIdentifierNotFound},
+		{name: "not a string", target: SyncTarget{GoVar: "Other"}, fails: true},
+		{name: "bad value", target: SyncTarget{GoVar: "Version", Value: "{{.Nope}}"}, fails: true, err: ErrInvalidTarget},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "version.go"), []byte(goVarSource), 0644); err != nil {
+				t.Fatal(err)
+			}
+			v := testVersion(t, "1.2.3")
+			v.Build = 7
+			test.target.File = "version.go"
+
+			changed, err := test.target.Sync(dir, v, test.prefix)
+			if test.fails {
+				if err == nil {
+					t.Fatal("Sync succeeded")
+				}
+				if test.err != nil && !errors.Is(err, test.err) {
+					t.Errorf("error is %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != test.changed {
+				t.Errorf("changed is %t, want %t", changed, test.changed)
+			}
+			contents, err := os.ReadFile(filepath.Join(dir, "version.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(contents), test.want) {
+				t.Errorf("version.go doesn't contain %s:\n%s", test.want, contents)
+			}
+		})
+	}
+}
+
+func TestSyncPattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   SyncTarget
+		contents string
+		want     string
+		err      error
+	}{
+		{name: "pattern", target: SyncTarget{Pattern: `Current version: v?([0-9.]+)`}, contents: "Current version: v1.0.0\n", want: "Current version: v1.2.3\n"},
+		{name: "template", target: SyncTarget{Template: "ARG VERSION={{.Version}}"}, contents: "ARG VERSION=1.0.0\n", want: "ARG VERSION=1.2.3\n"},
+		{name: "not found", target: SyncTarget{Template: "ARG VERSION={{.Version}}"}, contents: "FROM scratch\n", err: ErrPatternNotFound},
+		{name: "value without goVar", target: SyncTarget{Template: "ARG VERSION={{.Version}}", Value: "x"}, contents: "ARG VERSION=1.0.0\n", err: ErrInvalidTarget},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "file")
+			if err := os.WriteFile(path, []byte(test.contents), 0644); err != nil {
+				t.Fatal(err)
+			}
+			test.target.File = "file"
+
+			// The prefix only applies to goVar targets
+			_, err := test.target.Sync(dir, testVersion(t, "1.2.3"), "v")
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Errorf("error is %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			contents, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(contents) != test.want {
+				t.Errorf("file is %q, want %q", contents, test.want)
+			}
+		})
+	}
+}
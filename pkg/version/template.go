@@ -0,0 +1,32 @@
+package version
+
+import (
+	"github.com/Masterminds/semver"
+)
+
+// TemplateData is what templates are executed against, the version file with
+// .Version printed with a prefix
+type TemplateData struct {
+	*GoVersion
+	Version TemplateVersion
+}
+
+// TemplateVersion embeds the version to keep the semver methods, e.g.
+// {{.Version.Major}}
+type TemplateVersion struct {
+	*semver.Version
+	prefix string
+}
+
+// NewTemplateData returns the data for templates about v, with prefix in
+// front of the version, normally Settings.ValuePrefix
+func NewTemplateData(v *GoVersion, prefix string) TemplateData {
+	return TemplateData{
+		GoVersion: v,
+		Version:   TemplateVersion{Version: v.Version, prefix: prefix},
+	}
+}
+
+func (t TemplateVersion) String() string {
+	return t.prefix + t.Version.String()
+}
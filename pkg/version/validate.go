@@ -0,0 +1,25 @@
+package version
+
+import "fmt"
+
+// Validate checks the invariants decoding doesn't enforce, returning every
+// problem found. A text version file has no name, that has to be filled in
+// before validating it.
+func (v *GoVersion) Validate() []string {
+	var problems []string
+	if v.Version == nil {
+		problems = append(problems, "version is missing")
+	}
+	if v.Build < 0 {
+		problems = append(problems, fmt.Sprintf("build %d is negative", v.Build))
+	}
+	if v.ProjectName == "" {
+		problems = append(problems, "name is empty")
+	}
+	for i, entry := range v.History {
+		if entry.Version == nil {
+			problems = append(problems, fmt.Sprintf("history entry %d has no version", i+1))
+		}
+	}
+	return problems
+}
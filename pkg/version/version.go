@@ -0,0 +1,478 @@
+// Package version is the model behind gover: the version file, the formats it
+// can be stored in, and the changes the gover commands make to it. Nothing in
+// this package prints or exits, every failure is returned as an error.
+package version
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+)
+
+var (
+	ErrNoVersion         = errors.New("version is not set")
+	ErrInvalidPrerelease = errors.New("invalid semver prerelease label")
+	ErrInvalidMetadata   = errors.New("invalid semver build metadata")
+	ErrNegativeBuild     = errors.New("build number can't be negative")
+	ErrUnknownLevel      = errors.New("unknown bump level")
+	ErrNotPrerelease     = errors.New("version is not a prerelease")
+	ErrInvalidCandidate  = errors.New("invalid release candidate number")
+)
+
+// Where the build number comes from. An unset buildSource is the same as
+// BuildSourceManual.
+const (
+	BuildSourceManual   string = "manual"
+	BuildSourceGitCount string = "git-count"
+)
+
+// BuildSources lists the valid buildSource values
+var BuildSources = []string{BuildSourceManual, BuildSourceGitCount}
+
+// Levels accepted by Bump, lowest precedence last
+var Levels = []string{"major", "minor", "patch"}
+
+// Matches a single dot-separated prerelease identifier, numeric identifiers
+// can't have leading zeroes (semver spec item 9)
+var prereleaseIdentifier = regexp.MustCompile(`^(0|[1-9][0-9]*|[0-9]*[A-Za-z-][0-9A-Za-z-]*)$`)
+
+// Build metadata identifiers may have leading zeroes (semver spec item 10)
+var metadataPattern = regexp.MustCompile(`^[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*$`)
+
+// GoVersion is the contents of a version file
+type GoVersion struct {
+	// SchemaVersion is the shape of the file, see CurrentSchema
+	SchemaVersion int             `json:"schemaVersion,omitempty" jsonschema:"minimum=1"`
+	ProjectName   string          `json:"name"`
+	Version       *semver.Version `json:"version"`
+	VersionString string          `json:"versionString"`
+	Build         int             `json:"build" jsonschema:"minimum=0"`
+	History       []HistoryEntry  `json:"history,omitempty"`
+	HistoryLimit  int             `json:"historyLimit,omitempty"`
+	Undone        *HistoryEntry   `json:"undone,omitempty"`
+	Commit        string          `json:"commit,omitempty"`
+	// Author and Description let the file double as minimal project
+	// metadata, gover only stores them
+	Author      string `json:"author,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Extras are custom fields, kept as they are across bumps
+	Extras map[string]string `json:"extras,omitempty"`
+	// CalVer is set for projects versioned by date rather than semver levels
+	CalVer *CalVer `json:"calver,omitempty"`
+	// CreatedAt is when the version file was created, UpdatedAt when it was
+	// last written. Both are missing from files written by older versions of
+	// gover.
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	// Checksum covers every other field, see ComputeChecksum. It is only
+	// written when the Integrity setting is on or the file already had one.
+	Checksum string `json:"checksum,omitempty"`
+
+	// Settings in the version file take precedence over the config file
+	Settings
+}
+
+// Settings change how gover treats a project. They can be stored in the
+// version file or in the config file.
+type Settings struct {
+	SyncFiles   []SyncTarget `json:"syncFiles,omitempty"`
+	BuildSource string       `json:"buildSource,omitempty"`
+
+	// ResetBuildOnBump restarts the build number at 0 for each new version
+	ResetBuildOnBump bool `json:"resetBuildOnBump,omitempty"`
+	// BumpBuildOnVersionBump increments the build number with every version
+	// bump, it can't be combined with ResetBuildOnBump
+	BumpBuildOnVersionBump bool `json:"bumpBuildOnVersionBump,omitempty"`
+	// BuildFromCI sets the build number from the CI job's number on bumps
+	BuildFromCI bool `json:"buildFromCI,omitempty"`
+	// Changelog turns CHANGELOG.md updates on bumps off when false
+	Changelog *bool `json:"changelog,omitempty"`
+	// Hooks are shell commands run around bumps
+	Hooks *Hooks `json:"hooks,omitempty"`
+	// Backup keeps the previous version file as a .bak file on each write
+	Backup bool `json:"backup,omitempty"`
+	// BackupRetention keeps this many timestamped backups instead of a single
+	// .bak file, turning backups on when it is above 0
+	BackupRetention int `json:"backupRetention,omitempty" jsonschema:"minimum=0"`
+	// NoBackup turns backups off, whatever Backup and BackupRetention say
+	NoBackup bool `json:"noBackup,omitempty"`
+	// ConfirmBumps asks before writing any bump, ConfirmMajor only before
+	// major ones
+	ConfirmBumps bool `json:"confirmBumps,omitempty"`
+	ConfirmMajor bool `json:"confirmMajor,omitempty"`
+	// Integrity seals the version file with a checksum, so that edits made
+	// outside of gover are noticed
+	Integrity bool `json:"integrity,omitempty"`
+	// UpdateReadme rewrites version badges and mentions of the previous
+	// version in README.md on bumps
+	UpdateReadme bool `json:"updateReadme,omitempty"`
+	// AllowDirty lets bumps go ahead with uncommitted changes in the git
+	// working tree
+	AllowDirty bool `json:"allowDirty,omitempty"`
+	// LooseVersions reads versions given to init, set and imports with
+	// CoerceVersion, the same as passing --loose
+	LooseVersions bool `json:"looseVersions,omitempty"`
+	// TagPrefix goes in front of the version in tag names, read it with
+	// EffectiveTagPrefix
+	TagPrefix *string `json:"tagPrefix,omitempty"`
+	// VersionPrefix goes in front of the version wherever it is shown, and in
+	// tag names unless TagPrefix is set. The version file always stores the
+	// bare version.
+	VersionPrefix *string `json:"versionPrefix,omitempty"`
+}
+
+// DefaultTagPrefix is used when neither tagPrefix nor versionPrefix is set.
+// An empty tagPrefix means tags are bare versions.
+const DefaultTagPrefix string = "v"
+
+// DefaultVersionPrefix is shown in front of the version in text output when
+// versionPrefix isn't set. Without it, get and templates give the bare
+// version.
+const DefaultVersionPrefix string = "v"
+
+// EffectiveTagPrefix returns the tag prefix, falling back to the version
+// prefix and then DefaultTagPrefix
+func (s Settings) EffectiveTagPrefix() string {
+	switch {
+	case s.TagPrefix != nil:
+		return *s.TagPrefix
+	case s.VersionPrefix != nil:
+		return *s.VersionPrefix
+	}
+	return DefaultTagPrefix
+}
+
+// DisplayPrefix returns the prefix for versions in text output, or
+// DefaultVersionPrefix when versionPrefix isn't set
+func (s Settings) DisplayPrefix() string {
+	if s.VersionPrefix == nil {
+		return DefaultVersionPrefix
+	}
+	return *s.VersionPrefix
+}
+
+// ValuePrefix returns the prefix for versions given as values, by get and
+// in templates. These were always bare, so they only get a prefix once
+// versionPrefix is set.
+func (s Settings) ValuePrefix() string {
+	if s.VersionPrefix == nil {
+		return ""
+	}
+	return *s.VersionPrefix
+}
+
+// Override returns s with every setting that overrides sets replaced. The
+// boolean toggles can only be turned on this way, since an unset toggle
+// can't be told apart from false.
+func (s Settings) Override(overrides Settings) Settings {
+	if len(overrides.SyncFiles) > 0 {
+		s.SyncFiles = overrides.SyncFiles
+	}
+	if overrides.BuildSource != "" {
+		s.BuildSource = overrides.BuildSource
+	}
+	s.ResetBuildOnBump = s.ResetBuildOnBump || overrides.ResetBuildOnBump
+	s.BumpBuildOnVersionBump = s.BumpBuildOnVersionBump || overrides.BumpBuildOnVersionBump
+	s.BuildFromCI = s.BuildFromCI || overrides.BuildFromCI
+	if overrides.Changelog != nil {
+		s.Changelog = overrides.Changelog
+	}
+	if overrides.Hooks != nil {
+		s.Hooks = overrides.Hooks
+	}
+	s.Backup = s.Backup || overrides.Backup
+	if overrides.BackupRetention != 0 {
+		s.BackupRetention = overrides.BackupRetention
+	}
+	s.NoBackup = s.NoBackup || overrides.NoBackup
+	s.ConfirmBumps = s.ConfirmBumps || overrides.ConfirmBumps
+	s.ConfirmMajor = s.ConfirmMajor || overrides.ConfirmMajor
+	s.Integrity = s.Integrity || overrides.Integrity
+	s.UpdateReadme = s.UpdateReadme || overrides.UpdateReadme
+	s.AllowDirty = s.AllowDirty || overrides.AllowDirty
+	s.LooseVersions = s.LooseVersions || overrides.LooseVersions
+	if overrides.TagPrefix != nil {
+		s.TagPrefix = overrides.TagPrefix
+	}
+	if overrides.VersionPrefix != nil {
+		s.VersionPrefix = overrides.VersionPrefix
+	}
+	return s
+}
+
+// Clone returns a copy of v that shares nothing with it, so that v can be
+// changed while the copy keeps what it was before
+func (v *GoVersion) Clone() *GoVersion {
+	c := *v
+	c.Version = cloneSemver(v.Version)
+	if v.History != nil {
+		c.History = make([]HistoryEntry, len(v.History))
+		for i, entry := range v.History {
+			c.History[i] = entry.clone()
+		}
+	}
+	if v.Undone != nil {
+		undone := v.Undone.clone()
+		c.Undone = &undone
+	}
+	if v.Extras != nil {
+		c.Extras = make(map[string]string, len(v.Extras))
+		for key, value := range v.Extras {
+			c.Extras[key] = value
+		}
+	}
+	if v.CalVer != nil {
+		calver := *v.CalVer
+		c.CalVer = &calver
+	}
+	if v.CreatedAt != nil {
+		created := *v.CreatedAt
+		c.CreatedAt = &created
+	}
+	if v.UpdatedAt != nil {
+		updated := *v.UpdatedAt
+		c.UpdatedAt = &updated
+	}
+
+	c.SyncFiles = append([]SyncTarget(nil), v.SyncFiles...)
+	if v.Changelog != nil {
+		changelog := *v.Changelog
+		c.Changelog = &changelog
+	}
+	if v.Hooks != nil {
+		c.Hooks = &Hooks{
+			PreBump:  append([]string(nil), v.Hooks.PreBump...),
+			PostBump: append([]string(nil), v.Hooks.PostBump...),
+		}
+	}
+	c.TagPrefix = cloneString(v.TagPrefix)
+	c.VersionPrefix = cloneString(v.VersionPrefix)
+	return &c
+}
+
+func (e HistoryEntry) clone() HistoryEntry {
+	e.Previous = cloneSemver(e.Previous)
+	e.Version = cloneSemver(e.Version)
+	if e.PreviousBuild != nil {
+		build := *e.PreviousBuild
+		e.PreviousBuild = &build
+	}
+	return e
+}
+
+func cloneSemver(v *semver.Version) *semver.Version {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
+func cloneString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	c := *s
+	return &c
+}
+
+// BumpMajor increments the major version, resetting minor and patch
+func (v *GoVersion) BumpMajor() error {
+	if v.Version == nil {
+		return ErrNoVersion
+	}
+	newV := v.Version.IncMajor()
+	v.Version = &newV
+	return nil
+}
+
+// BumpMinor increments the minor version, resetting patch
+func (v *GoVersion) BumpMinor() error {
+	if v.Version == nil {
+		return ErrNoVersion
+	}
+	newV := v.Version.IncMinor()
+	v.Version = &newV
+	return nil
+}
+
+// BumpPatch increments the patch version, or releases a prerelease of the
+// same patch version
+func (v *GoVersion) BumpPatch() error {
+	if v.Version == nil {
+		return ErrNoVersion
+	}
+	newV := v.Version.IncPatch()
+	v.Version = &newV
+	return nil
+}
+
+// Bump increments the version by one of the names in Levels. Like the semver
+// Inc* functions underneath, any prerelease and metadata are dropped, so
+// bumping 1.3.0-rc.1 by patch yields 1.3.0.
+func (v *GoVersion) Bump(level string) error {
+	if v.CalVer != nil {
+		return fmt.Errorf("%w, there is no %s level in %s", ErrCalVerBump, level, v.CalVer.Pattern)
+	}
+	switch level {
+	case "major":
+		return v.BumpMajor()
+	case "minor":
+		return v.BumpMinor()
+	case "patch":
+		return v.BumpPatch()
+	}
+	return fmt.Errorf("%w '%s', valid levels are: %s", ErrUnknownLevel, level, strings.Join(Levels, ", "))
+}
+
+// ValidPrerelease reports whether label follows the semver prerelease rules
+func ValidPrerelease(label string) bool {
+	for _, identifier := range strings.Split(label, ".") {
+		if !prereleaseIdentifier.MatchString(identifier) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidMetadata reports whether metadata follows the semver build metadata
+// rules
+func ValidMetadata(metadata string) bool {
+	return metadataPattern.MatchString(metadata)
+}
+
+// SetPrerelease attaches label to the version, replacing any existing
+// prerelease identifier. An empty label clears it.
+func (v *GoVersion) SetPrerelease(label string) error {
+	if v.Version == nil {
+		return ErrNoVersion
+	}
+	if label != "" && !ValidPrerelease(label) {
+		return fmt.Errorf("%w '%s'", ErrInvalidPrerelease, label)
+	}
+
+	newV, err := v.Version.SetPrerelease(label)
+	if err != nil {
+		return err
+	}
+	v.Version = &newV
+	return nil
+}
+
+// SetMetadata attaches build metadata to the version. An empty string clears
+// it.
+func (v *GoVersion) SetMetadata(metadata string) error {
+	if v.Version == nil {
+		return ErrNoVersion
+	}
+	if metadata != "" && !ValidMetadata(metadata) {
+		return fmt.Errorf("%w '%s'", ErrInvalidMetadata, metadata)
+	}
+
+	newV, err := v.Version.SetMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	v.Version = &newV
+	return nil
+}
+
+// Release finalizes a prerelease into the stable version it precedes, so
+// 1.3.0-rc.3 becomes 1.3.0. Unlike BumpPatch the metadata is kept unless
+// clearMetadata is set.
+func (v *GoVersion) Release(clearMetadata bool) error {
+	if v.Version == nil {
+		return ErrNoVersion
+	}
+	if v.Version.Prerelease() == "" {
+		return fmt.Errorf("%w: %s", ErrNotPrerelease, v.Version)
+	}
+	if err := v.SetPrerelease(""); err != nil {
+		return err
+	}
+	if clearMetadata {
+		return v.SetMetadata("")
+	}
+	return nil
+}
+
+// CandidatePrefix is the prerelease label release candidates are numbered
+// under
+const CandidatePrefix string = "rc"
+
+// NextCandidate moves to the next release candidate. A stable version is
+// bumped by level and becomes rc.1, an existing rc.N becomes rc.N+1 and a bare
+// rc becomes rc.1, leaving the version itself alone. Any other prerelease,
+// like beta.2, moves to rc.1 of the same version as long as that sorts after
+// it.
+func (v *GoVersion) NextCandidate(level string) error {
+	if v.Version == nil {
+		return ErrNoVersion
+	}
+
+	label := v.Version.Prerelease()
+	next := CandidatePrefix + ".1"
+	switch {
+	case label == "":
+		if err := v.Bump(level); err != nil {
+			return err
+		}
+	case label == CandidatePrefix:
+	case strings.HasPrefix(label, CandidatePrefix+"."):
+		number := strings.TrimPrefix(label, CandidatePrefix+".")
+		n, err := strconv.Atoi(number)
+		if err != nil || n < 0 {
+			return fmt.Errorf("%w: '%s' in %s is not a number", ErrInvalidCandidate, number, label)
+		}
+		next = fmt.Sprintf("%s.%d", CandidatePrefix, n+1)
+	default:
+		candidate, err := v.Version.SetPrerelease(next)
+		if err != nil {
+			return err
+		}
+		if !candidate.GreaterThan(v.Version) {
+			return fmt.Errorf("%s sorts before %s, so it can't be the next prerelease", next, label)
+		}
+	}
+	return v.SetPrerelease(next)
+}
+
+// IncrementBuild adds one to the build number
+func (v *GoVersion) IncrementBuild() {
+	v.Build++
+}
+
+// SetBuild sets the build number
+func (v *GoVersion) SetBuild(build int) error {
+	if build < 0 {
+		return ErrNegativeBuild
+	}
+	v.Build = build
+	return nil
+}
+
+// Touch records a write of the version file at t. A missing CreatedAt is
+// backfilled with the oldest history entry, or t when there is no history.
+func (v *GoVersion) Touch(t time.Time) {
+	t = t.UTC().Truncate(time.Second)
+	if v.CreatedAt == nil {
+		created := t
+		if len(v.History) > 0 {
+			created = v.History[0].Timestamp
+		}
+		v.CreatedAt = &created
+	}
+	v.UpdatedAt = &t
+}
+
+// Hooks are shell commands run around a bump, in order. A failing pre bump
+// hook stops the bump before anything is written.
+type Hooks struct {
+	PreBump  []string `json:"preBump,omitempty"`
+	PostBump []string `json:"postBump,omitempty"`
+}
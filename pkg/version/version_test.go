@@ -0,0 +1,235 @@
+package version
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/semver"
+)
+
+func TestCloneSharesNothing(t *testing.T) {
+	created := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	createdAt := created
+	prefix := "release-"
+	v := &GoVersion{
+		ProjectName: "test",
+		Version:     semver.MustParse("1.2.3"),
+		History: []HistoryEntry{
+			{Previous: semver.MustParse("1.2.2"), Version: semver.MustParse("1.2.3"), Timestamp: created},
+		},
+		Extras:    map[string]string{"team": "core"},
+		CreatedAt: &createdAt,
+		Settings: Settings{
+			SyncFiles: []SyncTarget{{File: "version.go", GoVar: "Version"}},
+			Hooks:     &Hooks{PreBump: []string{"make test"}},
+			TagPrefix: &prefix,
+		},
+	}
+
+	c := v.Clone()
+	if err := v.BumpMinor(); err != nil {
+		t.Fatal(err)
+	}
+	v.RecordHistory(c)
+	v.History[0].VersionString = "changed"
+	v.Extras["team"] = "changed"
+	*v.CreatedAt = created.Add(time.Hour)
+	v.SyncFiles[0].File = "changed.go"
+	v.Hooks.PreBump[0] = "changed"
+	*v.TagPrefix = "changed"
+
+	switch {
+	case c.Version.String() != "1.2.3":
+		t.Errorf("clone version is %s, want 1.2.3", c.Version)
+	case len(c.History) != 1 || c.History[0].VersionString != "":
+		t.Errorf("clone history changed: %+v", c.History)
+	case c.Extras["team"] != "core":
+		t.Errorf("clone extras changed: %v", c.Extras)
+	case !c.CreatedAt.Equal(created):
+		t.Errorf("clone createdAt changed to %s", c.CreatedAt)
+	case c.SyncFiles[0].File != "version.go":
+		t.Errorf("clone syncFiles changed: %+v", c.SyncFiles)
+	case c.Hooks.PreBump[0] != "make test":
+		t.Errorf("clone hooks changed: %+v", c.Hooks)
+	case *c.TagPrefix != "release-":
+		t.Errorf("clone tagPrefix changed to %s", *c.TagPrefix)
+	}
+}
+
+func TestBump(t *testing.T) {
+	tests := []struct {
+		version string
+		level   string
+		want    string
+		err     error
+	}{
+		{version: "1.2.3", level: "major", want: "2.0.0"},
+		{version: "1.2.3", level: "minor", want: "1.3.0"},
+		{version: "1.2.3", level: "patch", want: "1.2.4"},
+		{version: "0.9.9", level: "minor", want: "0.10.0"},
+		// Prereleases and metadata are dropped
+		{version: "1.3.0-rc.1", level: "patch", want: "1.3.0"},
+		{version: "1.3.0-rc.1", level: "minor", want: "1.4.0"},
+		{version: "1.2.3+build.7", level: "patch", want: "1.2.4"},
+		{version: "1.2.3", level: "micro", err: ErrUnknownLevel},
+		{level: "patch", err: ErrNoVersion},
+	}
+	for _, test := range tests {
+		t.Run(test.version+" "+test.level, func(t *testing.T) {
+			v := &GoVersion{}
+			if test.version != "" {
+				v.Version = semver.MustParse(test.version)
+			}
+			err := v.Bump(test.level)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("error is %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.Version.String(); got != test.want {
+				t.Errorf("bumped to %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBumpCalVer(t *testing.T) {
+	v := &GoVersion{Version: semver.MustParse("2024.6.0"), CalVer: &CalVer{Pattern: "YYYY.MM.MICRO"}}
+	if err := v.Bump("minor"); !errors.Is(err, ErrCalVerBump) {
+		t.Errorf("error is %v, want %v", err, ErrCalVerBump)
+	}
+}
+
+func TestSetPrereleaseAndMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		set     func(v *GoVersion) error
+		want    string
+		err     error
+	}{
+		{name: "prerelease", version: "1.2.3", set: func(v *GoVersion) error { return v.SetPrerelease("beta.1") }, want: "1.2.3-beta.1"},
+		{name: "replace prerelease", version: "1.2.3-alpha", set: func(v *GoVersion) error { return v.SetPrerelease("beta") }, want: "1.2.3-beta"},
+		{name: "clear prerelease", version: "1.2.3-alpha+sha.1", set: func(v *GoVersion) error { return v.SetPrerelease("") }, want: "1.2.3+sha.1"},
+		{name: "leading zero prerelease", version: "1.2.3", set: func(v *GoVersion) error { return v.SetPrerelease("rc.01") }, err: ErrInvalidPrerelease},
+		{name: "empty prerelease identifier", version: "1.2.3", set: func(v *GoVersion) error { return v.SetPrerelease("rc..1") }, err: ErrInvalidPrerelease},
+		{name: "metadata", version: "1.2.3-rc.1", set: func(v *GoVersion) error { return v.SetMetadata("sha.0abc") }, want: "1.2.3-rc.1+sha.0abc"},
+		{name: "leading zero metadata", version: "1.2.3", set: func(v *GoVersion) error { return v.SetMetadata("001") }, want: "1.2.3+001"},
+		{name: "clear metadata", version: "1.2.3+sha.1", set: func(v *GoVersion) error { return v.SetMetadata("") }, want: "1.2.3"},
+		{name: "invalid metadata", version: "1.2.3", set: func(v *GoVersion) error { return v.SetMetadata("a_b") }, err: ErrInvalidMetadata},
+		{name: "no version", set: func(v *GoVersion) error { return v.SetPrerelease("rc") }, err: ErrNoVersion},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := &GoVersion{}
+			if test.version != "" {
+				v.Version = semver.MustParse(test.version)
+			}
+			err := test.set(v)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("error is %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.Version.String(); got != test.want {
+				t.Errorf("version is %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRelease(t *testing.T) {
+	tests := []struct {
+		version       string
+		clearMetadata bool
+		want          string
+		err           error
+	}{
+		{version: "1.3.0-rc.3", want: "1.3.0"},
+		{version: "1.3.0-rc.3+sha.1", want: "1.3.0+sha.1"},
+		{version: "1.3.0-rc.3+sha.1", clearMetadata: true, want: "1.3.0"},
+		{version: "1.3.0", err: ErrNotPrerelease},
+	}
+	for _, test := range tests {
+		t.Run(test.version, func(t *testing.T) {
+			v := &GoVersion{Version: semver.MustParse(test.version)}
+			err := v.Release(test.clearMetadata)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("error is %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.Version.String(); got != test.want {
+				t.Errorf("released %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNextCandidate(t *testing.T) {
+	tests := []struct {
+		version string
+		level   string
+		want    string
+		err     error
+	}{
+		{version: "1.2.3", level: "minor", want: "1.3.0-rc.1"},
+		{version: "1.2.3", level: "patch", want: "1.2.4-rc.1"},
+		{version: "1.3.0-rc.1", level: "minor", want: "1.3.0-rc.2"},
+		{version: "1.3.0-rc.9", level: "minor", want: "1.3.0-rc.10"},
+		{version: "1.3.0-rc", level: "minor", want: "1.3.0-rc.1"},
+		{version: "1.3.0-beta.2", level: "minor", want: "1.3.0-rc.1"},
+		{version: "1.3.0-rc.x", level: "minor", err: ErrInvalidCandidate},
+	}
+	for _, test := range tests {
+		t.Run(test.version+" "+test.level, func(t *testing.T) {
+			v := &GoVersion{Version: semver.MustParse(test.version)}
+			err := v.NextCandidate(test.level)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("error is %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.Version.String(); got != test.want {
+				t.Errorf("next candidate is %s, want %s", got, test.want)
+			}
+		})
+	}
+
+	// A prerelease sorting after rc has no rc of its own version to go to
+	v := &GoVersion{Version: semver.MustParse("1.3.0-tip")}
+	if err := v.NextCandidate("minor"); err == nil {
+		t.Errorf("NextCandidate moved 1.3.0-tip to %s", v.Version)
+	}
+}
+
+func TestSetBuild(t *testing.T) {
+	v := &GoVersion{Build: 4}
+	v.IncrementBuild()
+	if v.Build != 5 {
+		t.Errorf("build is %d after IncrementBuild, want 5", v.Build)
+	}
+	if err := v.SetBuild(-1); !errors.Is(err, ErrNegativeBuild) {
+		t.Errorf("error is %v, want %v", err, ErrNegativeBuild)
+	}
+	if v.Build != 5 {
+		t.Errorf("build is %d after a rejected SetBuild, want 5", v.Build)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Prefix of the executables on PATH that add commands, gover-foo is run
+// for `gover foo`
+const pluginPrefix string = "gover-"
+
+// Runs the gover-<name> executable on PATH with args, exiting with its exit
+// code. Returns when there's no such executable. The plugin gets the version
+// file in GOVER_FILE, and its version, name and build when it can be read.
+func runPlugin(name string, args []string) {
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, `/\`) {
+		return
+	}
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return
+	}
+
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), pluginEnv(name)...)
+	logVerbose("Running plugin %s", path)
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr) && exitErr.ExitCode() >= 0:
+		exit(exitErr.ExitCode())
+	case err != nil:
+		printError("Unable to run %s\n", path)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	exit(exitSuccess)
+}
+
+// Context for a plugin. A project without a version file, or with one that
+// can't be read, only gets GOVER_FILE.
+func pluginEnv(name string) []string {
+	resolveVersionFile(name, flag.NewFlagSet(name, flag.ContinueOnError))
+	env := []string{"GOVER_FILE=" + versionFile}
+
+	v, err := version.Load(versionFile)
+	if err != nil {
+		logVerbose("Not passing the version to the plugin: %s", err)
+		return env
+	}
+	loadConfig()
+	settings = config.Settings.Override(v.Settings)
+	nameTextProject(versionFile, v)
+	if v.Version != nil {
+		env = append(env, "GOVER_VERSION="+settings.ValuePrefix()+v.Version.String())
+	}
+	return append(env,
+		"GOVER_NAME="+v.ProjectName,
+		"GOVER_BUILD="+strconv.Itoa(v.Build),
+	)
+}
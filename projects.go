@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// A version file somewhere in the repository, for monorepos holding more
+// than one versioned project
+type project struct {
+	name string
+	dir  string
+	file string
+	v    *version.GoVersion
+	err  error
+}
+
+// Directories that hold dependencies or build output rather than projects
+var skipProjectDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+}
+
+// Top of the git repository containing the working directory. Projects are
+// only searched for inside a repository, so that running gover somewhere
+// like $HOME doesn't walk the whole tree.
+func projectRoot() (string, bool) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	for dir := cwd; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+		if filepath.Dir(dir) == dir {
+			return "", false
+		}
+	}
+}
+
+// Lists every version file under root, with dir relative to root. Hidden
+// and dependency directories are skipped unless all is set. Files that can't
+// be loaded are still listed, with the error.
+func walkProjects(root string, all bool) []project {
+	var projects []project
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path != root && entry != nil && entry.IsDir() {
+				rel, _ := filepath.Rel(root, path)
+				projects = append(projects, project{dir: filepath.ToSlash(rel), err: err})
+			}
+			return nil
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if !all && path != root && (strings.HasPrefix(entry.Name(), ".") || skipProjectDirs[entry.Name()]) {
+			return filepath.SkipDir
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		for _, file := range versionFilesIn(path) {
+			p := project{dir: filepath.ToSlash(rel), file: file}
+			p.v, p.err = version.Load(file)
+			if p.err == nil {
+				nameTextProject(file, p.v)
+				p.name = p.v.ProjectName
+			}
+			projects = append(projects, p)
+		}
+		return nil
+	})
+	return projects
+}
+
+// Lists the projects in the repository that --project can select, leaving
+// out directories with more than one version file
+func findProjects() []project {
+	root, ok := projectRoot()
+	if !ok {
+		return nil
+	}
+
+	files := make(map[string]int)
+	walked := walkProjects(root, false)
+	for _, p := range walked {
+		files[p.dir]++
+	}
+
+	var projects []project
+	for _, p := range walked {
+		if files[p.dir] == 1 && p.file != "" {
+			projects = append(projects, p)
+		}
+	}
+	return projects
+}
+
+func printProjects(projects []project) {
+	for _, p := range projects {
+		if p.name == "" {
+			fmt.Fprintf(os.Stderr, "  %s\n", p.dir)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "  %s (%s)\n", p.dir, p.name)
+	}
+}
+
+// Resolves --project to a version file. The selector is either a directory,
+// relative to the working directory or to the top of the repository, or a
+// project name. For init it has to be a directory, since there's no version
+// file to take the name from yet.
+func selectProject(selector string, init bool) string {
+	dirs := []string{selector}
+	if root, ok := projectRoot(); ok && !filepath.IsAbs(selector) {
+		dirs = append(dirs, filepath.Join(root, selector))
+	}
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		if init {
+			return filepath.Join(dir, versionFileName)
+		}
+		found := versionFilesIn(dir)
+		requireSingleVersionFile(dir, found)
+		if len(found) == 1 {
+			return found[0]
+		}
+	}
+	if init {
+		printError("Project directory '%s' doesn't exist\n", selector)
+		exit(exitUsage)
+	}
+
+	projects := findProjects()
+	var matches []project
+	for _, p := range projects {
+		if p.name == selector {
+			matches = append(matches, p)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0].file
+	}
+
+	if len(matches) > 1 {
+		printError("More than one project is named '%s', choose one by directory:\n", selector)
+		printProjects(matches)
+	} else {
+		printError("No project matches '%s'", selector)
+		if len(projects) > 0 {
+			fmt.Fprintln(os.Stderr, ", the projects are:")
+			printProjects(projects)
+		} else {
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+	exit(exitUsage)
+	return ""
+}
+
+// Exits listing the candidates when no version file applies to the working
+// directory but several projects exist further down the repository
+func requireProjectSelection() {
+	projects := findProjects()
+	if len(projects) < 2 {
+		return
+	}
+	printError("Found multiple projects, choose one with --project:\n")
+	printProjects(projects)
+	exit(exitUsage)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+const readmeFileName string = "README.md"
+
+// Static shields.io badges, https://img.shields.io/badge/<label>-<message>-<color>.
+// Dashes and underscores in the label and message are doubled.
+var badgePattern = regexp.MustCompile(`img\.shields\.io/badge/(?:[^-/\s)"']|--)*-v?((?:[0-9A-Za-z.+]|--|__)+)-`)
+
+// A complete version in a badge message, leading "v" excluded
+var badgeVersion = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+(?:[-+][0-9A-Za-z.+-]*[0-9A-Za-z])?$`)
+
+// Opening or closing fence of a fenced code block
+var codeFence = regexp.MustCompile("^ {0,3}(```|~~~)")
+
+type readmeLine struct {
+	number   int
+	old, new string
+}
+
+// Rewrites the version references in the README next to the version file:
+// static shields.io badges, whatever version they show, and mentions of the
+// stale version, e.g. "Current release: v1.2.3". Code blocks are left as
+// they are. Returns the lines that differ, which are only written when write
+// is set.
+func readmeChanges(v *version.GoVersion, stale *semver.Version, write bool) (string, []readmeLine) {
+	path := filepath.Join(filepath.Dir(versionFile), readmeFileName)
+	contents, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		logVerbose("No %s next to %s", readmeFileName, versionFile)
+		return path, nil
+	}
+	if err != nil {
+		printError("Unable to read %s\n", path)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+
+	lines := strings.Split(string(contents), "\n")
+	var changes []readmeLine
+	inFence, fence, previousBlank := false, "", true
+	for i, line := range lines {
+		if match := codeFence.FindStringSubmatch(line); match != nil {
+			if !inFence {
+				inFence, fence = true, match[1]
+			} else if match[1] == fence {
+				inFence = false
+			}
+			continue
+		}
+		// Indented code blocks start after a blank line
+		indented := strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "    ")
+		blank := strings.TrimSpace(line) == ""
+		if inFence || (indented && previousBlank) {
+			previousBlank = blank || previousBlank
+			continue
+		}
+		previousBlank = blank
+
+		updated := replaceBadgeVersions(line, v.Version)
+		if stale != nil && !stale.Equal(v.Version) {
+			updated = replaceVersion(updated, stale.String(), v.Version.String())
+		}
+		if updated != line {
+			changes = append(changes, readmeLine{number: i + 1, old: line, new: updated})
+			lines[i] = updated
+		}
+	}
+
+	if write && len(changes) > 0 {
+		info, err := os.Stat(path)
+		if err == nil {
+			err = os.WriteFile(path, []byte(strings.Join(lines, "\n")), info.Mode().Perm())
+		}
+		if err != nil {
+			printError("Unable to write %s\n", path)
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitFailure)
+		}
+	}
+	return path, changes
+}
+
+// Updates the README after a bump from previous, printing each changed
+// line. Returns the README path, or an empty string when nothing changed.
+func updateReadme(previous *version.GoVersion, v *version.GoVersion) string {
+	path, changes := readmeChanges(v, previous.Version, true)
+	if len(changes) == 0 {
+		logVerbose("%s has no version references to update", readmeFileName)
+		return ""
+	}
+	for _, change := range changes {
+		printInfo("Updated %s:%d\n  - %s\n  + %s\n", readmeFileName, change.number, change.old, change.new)
+	}
+	return path
+}
+
+// The version the README most likely still shows, the one before the
+// current version in the history
+func staleReadmeVersion(v *version.GoVersion) *semver.Version {
+	if len(v.History) == 0 {
+		return nil
+	}
+	last := v.History[len(v.History)-1]
+	if last.Version == nil || !last.Version.Equal(v.Version) {
+		return nil
+	}
+	return last.Previous
+}
+
+func replaceBadgeVersions(line string, current *semver.Version) string {
+	matches := badgePattern.FindAllStringSubmatchIndex(line, -1)
+	if len(matches) == 0 {
+		return line
+	}
+
+	var updated strings.Builder
+	last := 0
+	for _, match := range matches {
+		start, end := match[2], match[3]
+		message := strings.NewReplacer("--", "-", "__", "_").Replace(line[start:end])
+		if !badgeVersion.MatchString(message) {
+			continue
+		}
+		updated.WriteString(line[last:start])
+		updated.WriteString(strings.NewReplacer("-", "--", "_", "__").Replace(current.String()))
+		last = end
+	}
+	updated.WriteString(line[last:])
+	return updated.String()
+}
+
+// Replaces whole word occurrences of old, optionally preceded by a "v", so
+// that 1.2.3 doesn't match inside 11.2.3 or 1.2.3-rc.1
+func replaceVersion(line string, old string, replacement string) string {
+	var updated strings.Builder
+	last := 0
+	for offset := 0; ; {
+		i := strings.Index(line[offset:], old)
+		if i < 0 {
+			break
+		}
+		start := offset + i
+		end := start + len(old)
+		offset = end
+
+		before := start - 1
+		if before >= 0 && (line[before] == 'v' || line[before] == 'V') {
+			before--
+		}
+		if (before >= 0 && isVersionChar(line[before])) || continuesVersion(line[end:]) {
+			continue
+		}
+		updated.WriteString(line[last:start])
+		updated.WriteString(replacement)
+		last = end
+	}
+	updated.WriteString(line[last:])
+	return updated.String()
+}
+
+func isVersionChar(c byte) bool {
+	return c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// Whether the text after a match makes it part of a longer version, like a
+// prerelease or a fourth number. A trailing full stop doesn't.
+func continuesVersion(after string) bool {
+	if after == "" {
+		return false
+	}
+	if after[0] != '.' && isVersionChar(after[0]) {
+		return true
+	}
+	return len(after) > 1 && strings.IndexByte(".-+", after[0]) >= 0 && after[1] != '.' && isVersionChar(after[1])
+}
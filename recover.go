@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/subtlepseudonym/go-prompt"
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Restores a missing or unparseable version file from its newest backup
+// without asking, set by --recover
+var recoverBackup bool
+
+// Offers to restore the version file from its backup when loading it failed
+// with loadErr because it's missing or can't be parsed, and returns the
+// restored version. Returns nil, leaving loadErr to be reported, when there
+// is no usable backup or the restore is declined.
+func recoverFromBackup(loadErr error) *version.GoVersion {
+	var pathErr *fs.PathError
+	missing := errors.Is(loadErr, os.ErrNotExist)
+	if !missing && (errors.As(loadErr, &pathErr) || errors.Is(loadErr, version.ErrNewerSchema)) {
+		return nil
+	}
+	backupFile, err := version.LatestBackup(versionFile)
+	if err != nil {
+		return nil
+	}
+	contents, err := os.ReadFile(backupFile)
+	if err != nil {
+		logVerbose("Not recovering from %s: %s", backupFile, err)
+		return nil
+	}
+	backup, err := version.Decode(contents, version.FormatFor(versionFile))
+	if err != nil {
+		logVerbose("Not recovering from %s, it can't be parsed either: %s", backupFile, err)
+		return nil
+	}
+
+	problem := "can't be parsed"
+	if missing {
+		problem = "is missing"
+	}
+	printWarning("%s %s, but its backup %s has %s build %d. A write that was interrupted, or an edit made outside of gover, probably left it like this.\n", versionFile, problem, backupFile, displayVersion(backup.Version), backup.Build)
+	switch {
+	case recoverBackup:
+	case stdinIsTerminal() && prompt.ConfirmWithDefault(fmt.Sprintf("Restore %s from %s? (y/N)", versionFile, backupFile), false):
+	default:
+		fmt.Fprintln(os.Stderr, "Run again with --recover to restore it from the backup")
+		return nil
+	}
+
+	if dryRun {
+		printInfo("Dry run, %s was not restored from %s\n", versionFile, backupFile)
+		return backup
+	}
+	if heldLock == nil {
+		acquireLock()
+		defer releaseLock()
+	}
+	if err := version.Save(versionFile, backup); err != nil {
+		printError("Unable to restore %s from %s\n", versionFile, backupFile)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	// The backup is the same as the version file now
+	if err := os.Remove(backupFile); err != nil {
+		printWarning("Unable to remove %s after restoring from it: %s\n", backupFile, err)
+	}
+	printInfo("Restored %s from %s\n", versionFile, backupFile)
+	return backup
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// How long in-flight requests get to finish once a shutdown signal arrives
+const shutdownTimeout time.Duration = 5 * time.Second
+
+// Reloading the version file sets the settings global, so requests take
+// turns
+var serveMutex sync.Mutex
+
+// Body and status code of GET /version. The version file is read again each
+// time so that bumps show up without a restart.
+func versionResponse() ([]byte, int) {
+	serveMutex.Lock()
+	defer serveMutex.Unlock()
+
+	// Loaded like any command would, but the handler's goroutine can't
+	// exit, so every problem becomes a 500
+	v, err := version.Load(versionFile)
+	if err == nil {
+		err = prepareVersion(v)
+	}
+	var body []byte
+	if err == nil {
+		body, err = json.Marshal(v)
+	}
+	if err != nil {
+		body, _ = json.Marshal(map[string]string{"error": err.Error()})
+		return append(body, '\n'), http.StatusInternalServerError
+	}
+	return append(body, '\n'), http.StatusOK
+}
+
+func serveMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/version", getOnly(func(w http.ResponseWriter, r *http.Request) {
+		body, status := versionResponse()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(body)
+	}))
+	mux.HandleFunc("/healthz", getOnly(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "ok")
+	}))
+	return mux
+}
+
+func getOnly(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// Serves /version and /healthz on addr until SIGINT or SIGTERM
+func serveVersion(addr string) {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           serveMux(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		sig := <-signals
+		logVerbose("Received %s, shutting down", sig)
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			printWarning("Unable to shut down cleanly: %s\n", err)
+		}
+		close(done)
+	}()
+
+	printInfo("Serving %s on %s\n", versionFile, addr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		printError("Unable to serve on %s\n", addr)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	<-done
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Suffix stripped from a template's name to find the file it renders to
+const stampSuffix string = ".tmpl"
+
+// What stamp templates are executed against, the fields of the version file
+// plus when it was stamped and the commit it was stamped at
+type stampData struct {
+	version.TemplateData
+	// Timestamp is when the version file was last written, so that stamping
+	// the same version twice gives the same file. Files from gover versions
+	// that didn't record it use the current time.
+	Timestamp time.Time
+	// SHA is HEAD's short hash, with -dirty for uncommitted changes
+	SHA string
+}
+
+func newStampData(v *version.GoVersion) stampData {
+	data := stampData{
+		TemplateData: version.NewTemplateData(v, settings.ValuePrefix()),
+		Timestamp:    now().UTC(),
+	}
+	if v.UpdatedAt != nil {
+		data.Timestamp = v.UpdatedAt.UTC()
+	}
+	if hash, err := gitCommitHash(); err == nil {
+		data.SHA = hash
+	} else {
+		logVerbose("No git commit for .SHA: %s", err)
+	}
+	return data
+}
+
+// Where a template renders to, output when given and otherwise the template
+// without its .tmpl suffix
+func stampTarget(path, output string) string {
+	if output != "" {
+		return output
+	}
+	return strings.TrimSuffix(path, stampSuffix)
+}
+
+// Renders every template and writes the results. With check nothing is
+// written, and stamp exits 1 if any rendered file differs from the one on
+// disk.
+func stampFiles(v *version.GoVersion, paths []string, output string, check bool) {
+	if len(paths) == 0 {
+		printError("Missing template, usage: gover stamp [--output <file>] <template>...\n")
+		exit(exitUsage)
+	}
+	if output != "" && len(paths) > 1 {
+		printError("--output can only be used with a single template\n")
+		exit(exitUsage)
+	}
+	for _, path := range paths {
+		if output == "" && !strings.HasSuffix(path, stampSuffix) {
+			printError("%s doesn't end in %s, use --output to name the rendered file\n", path, stampSuffix)
+			exit(exitUsage)
+		}
+	}
+
+	data := newStampData(v)
+	stale := false
+	for _, path := range paths {
+		target := stampTarget(path, output)
+		rendered := renderStamp(path, data)
+
+		existing, err := os.ReadFile(target)
+		if err != nil && !os.IsNotExist(err) {
+			printError("Unable to read %s\n", target)
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitFailure)
+		}
+		if err == nil && bytes.Equal(existing, rendered) {
+			logVerbose("%s is up to date", target)
+			continue
+		}
+
+		switch {
+		case check && os.IsNotExist(err):
+			fmt.Fprintf(stdout, "%s is missing, stamp %s to create it\n", target, path)
+			stale = true
+		case check:
+			fmt.Fprintf(stdout, "%s is out of date with %s\n", target, path)
+			stale = true
+		case dryRun:
+			printInfo("Dry run, %s was not written\n", target)
+			fmt.Fprint(stdout, string(rendered))
+		default:
+			if err := os.WriteFile(target, rendered, stampMode(path)); err != nil {
+				printError("Unable to write %s\n", target)
+				fmt.Fprintln(os.Stderr, err)
+				exit(exitFailure)
+			}
+			printInfo("Stamped %s from %s\n", target, path)
+		}
+	}
+	if stale {
+		exit(exitFailure)
+	}
+}
+
+func renderStamp(path string, data stampData) []byte {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		printError("Unable to read template %s\n", path)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitFailure)
+	}
+	// parseTemplate would repeat the whole file in its error
+	tmpl, err := template.New(path).Option("missingkey=error").Parse(string(text))
+	if err != nil {
+		printError("Unable to parse template %s\n", path)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitUsage)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		printError("Unable to render %s\n", path)
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitUsage)
+	}
+	return out.Bytes()
+}
+
+// Rendered files get the template's permissions, so that a stamped script
+// stays executable
+func stampMode(path string) os.FileMode {
+	if info, err := os.Stat(path); err == nil {
+		return info.Mode().Perm()
+	}
+	return 0644
+}
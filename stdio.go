@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Reads the version JSON from stdin and writes it to stdout instead of the
+// version file, set by --stdio
+var stdioMode bool
+
+// Stands in for the version file path in messages and for finding the config
+// file, which is looked up in the working directory
+const stdioFileName string = "stdin"
+
+// The real stdout, kept for the version JSON once human output is moved to
+// stderr
+var stdioOutput io.Writer = os.Stdout
+
+// Rejects flags that name a version file or want stdout for themselves.
+// GOVER_FILE is ignored rather than rejected, it's usually set for a whole
+// CI job.
+func checkStdioFlags(name string, fs *flag.FlagSet) {
+	fileGiven := func(f *flag.Flag) {
+		if f.Name == "file" || f.Name == "f" {
+			explicitFile = true
+		}
+	}
+	flag.Visit(fileGiven)
+	fs.Visit(fileGiven)
+
+	switch {
+	case name == "init":
+		printError("--stdio reads an existing version from stdin, init can't be used with it\n")
+		exit(exitUsage)
+	case explicitFile || projectSelector != "":
+		printError("--stdio can't be used with --file or --project\n")
+		exit(exitUsage)
+	case jsonOutput || outputFormat != "" || quietOutput:
+		printError("--stdio writes the version file to stdout, it can't be used with --json, --format or --quiet\n")
+		exit(exitUsage)
+	}
+	versionFile = stdioFileName
+}
+
+// Moves everything a changing command prints to stderr, so that stdout only
+// carries the version JSON
+func startStdioOutput() {
+	stdout = os.Stderr
+	os.Stdout = os.Stderr
+}
+
+// Returns the contents read as well, stdin can't be read twice
+func readStdinVersion() (*version.GoVersion, []byte, error) {
+	contents, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, nil, err
+	}
+	v, err := version.Decode(contents, version.JSON)
+	if err != nil {
+		return nil, contents, fmt.Errorf("unable to parse %s: %w", stdioFileName, err)
+	}
+	return v, contents, nil
+}
+
+func writeStdoutVersion(v *version.GoVersion) error {
+	contents, err := version.Encode(v, version.JSON)
+	if err != nil {
+		return err
+	}
+	_, err = stdioOutput.Write(append(contents, '\n'))
+	return err
+}
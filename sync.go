@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Copies the version into every syncFiles target and returns the files that
+// changed. Each target is reported on its own, a target that no longer
+// matches is a warning and any other failure exits once all targets have
+// been tried.
+func syncVersionFiles(v *version.GoVersion) []string {
+	dir := filepath.Dir(versionFile)
+
+	var updated []string
+	var failed bool
+	for _, target := range settings.SyncFiles {
+		changed, err := target.Sync(dir, v, settings.ValuePrefix())
+		if errors.Is(err, version.ErrPatternNotFound) {
+			printWarning("%s doesn't contain the version to replace, not updated\n", target.File)
+			continue
+		}
+		if err != nil {
+			printError("Unable to update %s\n", target.File)
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+		if changed {
+			logVerbose("Updated %s to %s", target.File, displayVersion(v.Version))
+			updated = append(updated, target.File)
+		}
+	}
+
+	if failed {
+		exit(exitFailure)
+	}
+	return updated
+}
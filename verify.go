@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/subtlepseudonym/gover/pkg/version"
+)
+
+// Outcome of one of verify's checks
+type releaseCheck struct {
+	description string
+	passed      bool
+	skipped     bool
+	detail      string
+}
+
+// Checks that the tag for the current version exists, is part of HEAD's
+// history and has the same version in its version file, and with remote
+// that the remote has the same tag. Prints a line per check and exits 1 if
+// any of them failed.
+func verifyRelease(v *version.GoVersion, remote bool) {
+	requireGitRepo()
+	name := tagName(v)
+
+	var checks []releaseCheck
+	exists := gitTagExists(name)
+	checks = append(checks, releaseCheck{
+		description: fmt.Sprintf("tag %s exists", name),
+		passed:      exists,
+		detail:      "create it with `gover tag`",
+	})
+	checks = append(checks, tagAncestorCheck(name, exists))
+	checks = append(checks, tagVersionCheck(v, name, exists))
+	if remote {
+		checks = append(checks, remoteTagCheck(name, exists))
+	}
+
+	failed := false
+	for _, check := range checks {
+		status := colorize(stdout, colorGreen, "PASS")
+		switch {
+		case check.skipped:
+			status = colorize(stdout, colorYellow, "SKIP")
+		case !check.passed:
+			status = colorize(stdout, colorRed, "FAIL")
+			failed = true
+		}
+		fmt.Fprintf(stdout, "%s  %s\n", status, check.description)
+		if !check.passed && check.detail != "" {
+			fmt.Fprintf(stdout, "      %s\n", check.detail)
+		}
+	}
+	if failed {
+		exit(exitFailure)
+	}
+}
+
+func tagAncestorCheck(name string, exists bool) releaseCheck {
+	check := releaseCheck{description: fmt.Sprintf("%s is HEAD or one of its ancestors", name), skipped: !exists}
+	if !exists {
+		return check
+	}
+	if _, err := runGit("merge-base", "--is-ancestor", "refs/tags/"+name+"^{commit}", "HEAD"); err != nil {
+		commit, _ := runGit("rev-parse", "--short", "refs/tags/"+name+"^{commit}")
+		check.detail = fmt.Sprintf("it points at %s, which isn't in HEAD's history", commit)
+		return check
+	}
+	check.passed = true
+	return check
+}
+
+func tagVersionCheck(v *version.GoVersion, name string, exists bool) releaseCheck {
+	rel, _ := repoRelativeVersionFile()
+	check := releaseCheck{description: fmt.Sprintf("%s at %s has version %s", rel, name, v.Version), skipped: !exists}
+	if !exists {
+		return check
+	}
+	tagged, ok := versionAt("refs/tags/" + name)
+	switch {
+	case !ok:
+		check.detail = fmt.Sprintf("%s doesn't exist at %s", rel, name)
+	case tagged.Version.String() != v.Version.String():
+		check.detail = fmt.Sprintf("it has version %s", tagged.Version)
+	default:
+		check.passed = true
+	}
+	return check
+}
+
+// The remote's tag has to be the same object as the local one, a tag that
+// was moved locally but not pushed again doesn't count
+func remoteTagCheck(name string, exists bool) releaseCheck {
+	remote := pushRemote("")
+	check := releaseCheck{description: fmt.Sprintf("%s has the same tag %s", remote, name)}
+
+	out, err := runGit("ls-remote", "--tags", remote, "refs/tags/"+name)
+	if err != nil {
+		check.detail = err.Error()
+		return check
+	}
+	if out == "" {
+		check.detail = fmt.Sprintf("push it with `git push %s refs/tags/%s`", remote, name)
+		return check
+	}
+	if exists {
+		local, _ := runGit("rev-parse", "refs/tags/"+name)
+		remoteHash := strings.Fields(out)[0]
+		if local != remoteHash {
+			check.detail = fmt.Sprintf("%s has %s, the local tag is %s", remote, shortHash(remoteHash), shortHash(local))
+			return check
+		}
+	}
+	check.passed = true
+	return check
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}